@@ -1,51 +1,45 @@
 package main
 
 import (
+	"context"
+	"log"
 	"net/http"
 
 	"github.com/nimgo/nim"
+	"github.com/nimgo/nim/nimble"
+	"github.com/nimgo/nim/nimble/router"
 )
 
+type contextKey string
+
 func main() {
-	//router := mux.NewRouter()
-	//router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-	//	w.Write([]byte("Welcome to Nimble!"))
-	//})
-	//router.HandleFunc("/about", aboutFunc)
-	//
-	//subrouter := mux.NewRouter()
-	//subrouter.HandleFunc("/p/iron_man", saysHi("Iron Man"))
-	//subrouter.HandleFunc("/p/captain_america", saysHi("Captain America"))
-	//router.PathPrefix("/p").Handler(nim.New().
-	//	UseFunc(subMiddleware).
-	//	Use(subrouter),
-	//)
-
-	n := nim.Default()
-	n.WithFunc(saysHi("alibaba"))
-
-	nim.Run(n, ":3000")
-
-	//n := nim.DefaultWithContext(context.TODO())
-	//n.UseFunc(myMiddleware)
-	//n.Use(router)
-	//n.Run(":3000")
+	rt := router.New()
+	rt.Get("/", saysHi("alibaba"))
+	rt.Get("/about", aboutFunc)
+	rt.Get("/p/{who}", func(w http.ResponseWriter, r *http.Request) {
+		saysHi(router.Param(r, "who"))(w, r)
+	}, subMiddleware)
+
+	appContext := context.WithValue(context.Background(), contextKey("team"), "the Avengers")
+
+	n := nim.DefaultWithContext(appContext)
+	n.WithFuncContinue(myMiddleware)
+	n.With(rt)
+
+	log.Fatal(nim.Run(n, ":3000"))
 }
 
 func aboutFunc(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("This is a lean, mean server."))
 }
 
-//func myMiddleware(w http.ResponseWriter, r *http.Request) {
-//	w.Write([]byte("A middleware that always runs per http request.\n\n"))
-//
-//	c := nim.GetContext(r)
-//	info := "ip = " + c.Value("ip").(string) + " port = " + c.Value("port").(string) + "\n\n"
-//	w.Write([]byte(info))
-//
-//	c = context.WithValue(c, "key", "the Avengers")
-//	nim.SetContext(r, c)
-//}
+func myMiddleware(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte("A middleware that always runs per http request.\n\n"))
+
+	c := nimble.GetContext(r)
+	c = context.WithValue(c, contextKey("key"), c.Value(contextKey("team")))
+	nimble.SetContext(r, c)
+}
 
 func saysHi(who string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -53,10 +47,9 @@ func saysHi(who string) http.HandlerFunc {
 	}
 }
 
-//
-//func subMiddleware(w http.ResponseWriter, r *http.Request) {
-//	c := nim.GetContext(r)
-//	if value, ok := c.Value("key").(string); ok {
-//		w.Write([]byte("SubMiddleware: Presenting to you " + value + "\n\n"))
-//	}
-//}
+func subMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if value, ok := r.Context().Value(contextKey("key")).(string); ok {
+		w.Write([]byte("SubMiddleware: Presenting to you " + value + "\n\n"))
+	}
+	next(w, r)
+}
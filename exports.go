@@ -1,9 +1,8 @@
 package nim
 
 import (
-	"log"
+	"context"
 	"net/http"
-	"os"
 
 	"github.com/nimgo/nim/nimble"
 	"github.com/nimgo/nim/nimware"
@@ -16,7 +15,17 @@ import (
 // Logger - Request/Response Logging
 // Static - Static File Serving
 func Default() *nimble.Nimble {
-	return nimble.New().
+	return withDefaults(nimble.New())
+}
+
+// DefaultWithContext returns a new Nimble instance like Default, where
+// the values of the application context are available from every request context.
+func DefaultWithContext(ctx context.Context) *nimble.Nimble {
+	return withDefaults(nimble.New().WithHandlerFunc(nimble.SeedContext(ctx)))
+}
+
+func withDefaults(n *nimble.Nimble) *nimble.Nimble {
+	return n.
 		WithHandler(nimware.NewRecovery()).
 		WithHandler(nimware.NewColorLogger()).
 		WithHandler(nimware.NewStatic(http.Dir("static")))
@@ -29,9 +38,16 @@ func New() *nimble.Nimble {
 
 // Run is a convenience function that runs the nimble stack as an HTTP
 // server. The addr string takes the same format as http.ListenAndServe.
-func Run(n *nimble.Nimble, addr ...string) {
-	l := log.New(os.Stdout, "[n.] ", 0)
-	address := detectAddress(addr...)
-	l.Printf("Server is listening on %s", address)
-	l.Fatal(http.ListenAndServe(address, n))
+// The server shuts down gracefully on SIGINT or SIGTERM, and Run returns
+// nil once in-flight requests have drained.
+func Run(n *nimble.Nimble, addr ...string) error {
+	return NewServer(n, addr...).ListenAndServe()
+}
+
+// RunWith is a convenience function that runs the nimble stack as an HTTP
+// server configured by the options. It behaves like Run otherwise.
+//
+//	nim.RunWith(n, nim.Addr(":8443"), nim.ReadHeaderTimeout(5*time.Second), nim.TLS(cert, key))
+func RunWith(n *nimble.Nimble, opts ...Option) error {
+	return NewServer(n).Configure(opts...).ListenAndServe()
 }
@@ -1,10 +1,23 @@
 package nim
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
 	"testing"
+
+	"github.com/nimgo/nim/nimble"
 )
 
+/* Test Helpers */
+func expect(t *testing.T, a interface{}, b interface{}) {
+	if a != b {
+		t.Errorf("Expected %v (type %v) - Got %v (type %v)", b, reflect.TypeOf(b), a, reflect.TypeOf(a))
+	}
+}
+
 func TestNimRun(t *testing.T) {
 	// just test that Run doesn't bomb
 	go Run(New(), ":3000")
@@ -28,3 +41,17 @@ func TestDetectAddress(t *testing.T) {
 		t.Error("Expected the PORT env var with a prefixed colon")
 	}
 }
+
+func TestNimDefaultWithContext(t *testing.T) {
+	type key string
+	app := context.WithValue(context.Background(), key("dbname"), "nimdb")
+
+	rec := httptest.NewRecorder()
+	n := DefaultWithContext(app)
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(nimble.GetContext(r).Value(key("dbname")).(string)))
+	})
+
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expect(t, rec.Body.String(), "nimdb")
+}
@@ -0,0 +1,169 @@
+package nimble
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+)
+
+// BufferedWriter is a Writer that holds the status and body of a response in memory,
+// up to a size limit, instead of sending them right away. Middleware can inspect and
+// rewrite the status, headers and body after the next handler has returned, then send
+// the response with Commit. Once the body grows past the limit, or the handler flushes,
+// the buffered response is sent and the rest of it is streamed as it is written.
+//
+//	func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+//		bw := nimble.Buffer(w, 1<<20)
+//		defer bw.Commit()
+//		next(bw, r)
+//		if !bw.Spilled() && bw.Status() == http.StatusNotFound {
+//			bw.SetBody([]byte("custom not found page"))
+//		}
+//	}
+type BufferedWriter struct {
+	Writer
+	limit   int
+	status  int
+	size    int
+	body    []byte
+	spilled bool
+}
+
+// Buffer returns a BufferedWriter wrapping w that holds responses of up to limit bytes.
+// When w is not a Writer, as outside a Nimble stack, functions added with After never run.
+func Buffer(w http.ResponseWriter, limit int) *BufferedWriter {
+	ww, ok := w.(Writer)
+	if !ok {
+		ww = newWriter(w)
+	}
+	return &BufferedWriter{Writer: ww, limit: limit}
+}
+
+func (bw *BufferedWriter) WriteHeader(s int) {
+	if bw.status != 0 || bw.Hijacked() {
+		return
+	}
+	if s >= 100 && s < 200 && s != http.StatusSwitchingProtocols {
+		// informational responses such as 103 Early Hints precede the final status
+		bw.Writer.WriteHeader(s)
+		return
+	}
+	bw.status = s
+	if bw.spilled {
+		bw.Writer.WriteHeader(s)
+	}
+}
+
+func (bw *BufferedWriter) Write(b []byte) (int, error) {
+	if bw.Hijacked() {
+		return 0, http.ErrHijacked
+	}
+	if bw.status == 0 {
+		bw.WriteHeader(http.StatusOK)
+	}
+	bw.size += len(b)
+
+	if bw.spilled {
+		return bw.Writer.Write(b)
+	}
+	bw.body = append(bw.body, b...)
+	if len(bw.body) > bw.limit {
+		if err := bw.Commit(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Status returns the status code written by the handler, or set with SetStatus.
+func (bw *BufferedWriter) Status() int {
+	if bw.status == 0 && bw.Hijacked() {
+		return bw.Writer.Status()
+	}
+	return bw.status
+}
+
+// Written returns whether or not the handler has written the status.
+func (bw *BufferedWriter) Written() bool {
+	return bw.Status() != 0
+}
+
+// Size returns the size of the response body written by the handler.
+func (bw *BufferedWriter) Size() int {
+	return bw.size
+}
+
+// Spilled returns whether the response has been sent, after which the status,
+// headers and body can no longer be changed.
+func (bw *BufferedWriter) Spilled() bool {
+	return bw.spilled
+}
+
+// Body returns the buffered response body.
+func (bw *BufferedWriter) Body() []byte {
+	return bw.body
+}
+
+// SetStatus replaces the status code of the buffered response.
+func (bw *BufferedWriter) SetStatus(s int) {
+	if !bw.spilled {
+		bw.status = s
+	}
+}
+
+// SetBody replaces the buffered response body, updating the Content-Length header if it is set.
+func (bw *BufferedWriter) SetBody(b []byte) {
+	if bw.spilled {
+		return
+	}
+	bw.body = b
+	if bw.Header().Get("Content-Length") != "" {
+		bw.Header().Set("Content-Length", strconv.Itoa(len(b)))
+	}
+}
+
+// Commit sends the buffered response and switches to streaming. It does nothing
+// if the response has already been sent, if nothing has been written or if the
+// connection has been hijacked.
+func (bw *BufferedWriter) Commit() error {
+	if bw.spilled || bw.status == 0 || bw.Hijacked() {
+		return nil
+	}
+	bw.spilled = true
+
+	bw.Writer.WriteHeader(bw.status)
+	body := bw.body
+	bw.body = nil
+	if len(body) == 0 {
+		return nil
+	}
+	_, err := bw.Writer.Write(body)
+	return err
+}
+
+// Flush sends the buffered response and streams the rest of it.
+func (bw *BufferedWriter) Flush() {
+	if bw.Hijacked() {
+		return
+	}
+	if bw.status == 0 {
+		bw.WriteHeader(http.StatusOK)
+	}
+	bw.Commit()
+	bw.Writer.Flush()
+}
+
+func (bw *BufferedWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := bw.Writer.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the ResponseWriter doesn't support the http.Hijack interface")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the wrapped Writer for use by http.ResponseController.
+func (bw *BufferedWriter) Unwrap() http.ResponseWriter {
+	return bw.Writer
+}
@@ -0,0 +1,135 @@
+package nimble
+
+import (
+	"fmt"
+	"net/http"
+
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBufferedWriterHoldsResponse(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := Buffer(rec, 64)
+
+	bw.Header().Set("Content-Length", "5")
+	bw.WriteHeader(http.StatusNotFound)
+	bw.Write([]byte("hello"))
+
+	expect(t, rec.Body.Len(), 0)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, bw.Written(), true)
+	expect(t, bw.Status(), http.StatusNotFound)
+	expect(t, string(bw.Body()), "hello")
+
+	bw.SetStatus(http.StatusGone)
+	bw.SetBody([]byte("gone for good"))
+	bw.Header().Set("X-Rewritten", "true")
+	expect(t, bw.Commit(), nil)
+
+	expect(t, rec.Code, http.StatusGone)
+	expect(t, rec.Body.String(), "gone for good")
+	expect(t, rec.Header().Get("Content-Length"), "13")
+	expect(t, rec.Header().Get("X-Rewritten"), "true")
+	expect(t, bw.Spilled(), true)
+}
+
+// codesRecorder records every status written, including informational ones.
+type codesRecorder struct {
+	*httptest.ResponseRecorder
+	codes []int
+}
+
+func (r *codesRecorder) WriteHeader(s int) {
+	r.codes = append(r.codes, s)
+	if s >= 200 {
+		r.ResponseRecorder.WriteHeader(s)
+	}
+}
+
+func TestBufferedWriterEarlyHints(t *testing.T) {
+	rec := &codesRecorder{ResponseRecorder: httptest.NewRecorder()}
+	bw := Buffer(rec, 64)
+
+	bw.WriteHeader(http.StatusEarlyHints)
+	expect(t, bw.Written(), false)
+	bw.WriteHeader(http.StatusCreated)
+	bw.Write([]byte("hello"))
+	expect(t, bw.Status(), http.StatusCreated)
+	expect(t, bw.Commit(), nil)
+
+	expect(t, fmt.Sprint(rec.codes), "[103 201]")
+	expect(t, rec.Code, http.StatusCreated)
+	expect(t, rec.Body.String(), "hello")
+}
+
+func TestBufferedWriterSpills(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := Buffer(rec, 4)
+
+	bw.Write([]byte("abc"))
+	expect(t, bw.Spilled(), false)
+	bw.Write([]byte("def"))
+	expect(t, bw.Spilled(), true)
+	expect(t, rec.Body.String(), "abcdef")
+
+	bw.Write([]byte("ghi"))
+	bw.SetBody([]byte("ignored"))
+	bw.Commit()
+	expect(t, rec.Body.String(), "abcdefghi")
+	expect(t, bw.Size(), 9)
+}
+
+func TestBufferedWriterFlushStreams(t *testing.T) {
+	rec := httptest.NewRecorder()
+	bw := Buffer(rec, 64)
+
+	bw.Write([]byte("event"))
+	bw.Flush()
+	expect(t, bw.Spilled(), true)
+	expect(t, rec.Flushed, true)
+	expect(t, rec.Body.String(), "event")
+}
+
+func TestBufferedWriterCommitEmpty(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newWriter(rec)
+	bw := Buffer(w, 64)
+
+	expect(t, bw.Commit(), nil)
+	expect(t, bw.Spilled(), false)
+	expect(t, w.Written(), false)
+}
+
+func TestBufferedWriterInChain(t *testing.T) {
+	n := New()
+	n.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		bw := Buffer(w, 1024)
+		defer bw.Commit()
+		next(bw, r)
+		if bw.Status() == http.StatusNotFound {
+			bw.Header().Set("Content-Type", "text/plain")
+			bw.SetBody([]byte("custom not found"))
+		}
+	})
+	n.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.(Writer).Before(func(w Writer) {
+			w.Header().Set("X-Status", http.StatusText(w.Status()))
+		})
+		next(w, r)
+	})
+	n.With(http.NotFoundHandler())
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expect(t, rec.Code, http.StatusNotFound)
+	expect(t, rec.Body.String(), "custom not found")
+	expect(t, rec.Header().Get("Content-Type"), "text/plain")
+	expect(t, rec.Header().Get("X-Status"), "Not Found")
+}
+
+func TestBufferedWriterUnwrap(t *testing.T) {
+	w := newWriter(httptest.NewRecorder())
+	bw := Buffer(w, 64)
+	expect(t, bw.Unwrap(), http.ResponseWriter(w))
+}
@@ -0,0 +1,85 @@
+package nimble
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const stateKey contextKey = iota
+
+// requestState holds a context set with SetContext until it is passed down the stack.
+type requestState struct {
+	ctx context.Context
+}
+
+// GetContext returns the context of the request, including any context
+// set with SetContext that has not yet been passed down the stack.
+func GetContext(r *http.Request) context.Context {
+	if s := stateOf(r); s != nil && s.ctx != nil {
+		return s.ctx
+	}
+	return r.Context()
+}
+
+// SetContext replaces the context of the request for the rest of the middleware
+// stack. The next middleware, and every one after it, receives a copy of the request
+// carrying ctx. It has no effect on requests that are not served by a Nimble stack.
+//
+//	c := nimble.GetContext(r)
+//	nimble.SetContext(r, context.WithValue(c, userKey, user))
+func SetContext(r *http.Request, ctx context.Context) {
+	if s := stateOf(r); s != nil {
+		s.ctx = ctx
+	}
+}
+
+// SeedContext returns a nimble.HandlerFunc that makes the values of the application
+// context available from the context of every request. Request values take precedence,
+// while deadlines and cancellation still come from the request.
+func SeedContext(app context.Context) HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		next(w, r.WithContext(valuesContext{r.Context(), app}))
+	}
+}
+
+// valuesContext looks up values in its context first, then in the values context.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+func (c valuesContext) Value(key interface{}) interface{} {
+	if v := c.Context.Value(key); v != nil {
+		return v
+	}
+	return c.values.Value(key)
+}
+
+// withState attaches a requestState to the request if it does not already carry one.
+func withState(r *http.Request) *http.Request {
+	if r == nil || stateOf(r) != nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), stateKey, &requestState{}))
+}
+
+// withPending returns the request carrying the context set with SetContext, if any.
+func withPending(r *http.Request) *http.Request {
+	s := stateOf(r)
+	if s == nil || s.ctx == nil {
+		return r
+	}
+	ctx := s.ctx
+	s.ctx = nil
+	return r.WithContext(ctx)
+}
+
+func stateOf(r *http.Request) *requestState {
+	if r == nil {
+		return nil
+	}
+	s, _ := r.Context().Value(stateKey).(*requestState)
+	return s
+}
@@ -0,0 +1,64 @@
+package nimble
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type testKey string
+
+func TestSetContextPropagates(t *testing.T) {
+	rec := httptest.NewRecorder()
+	result := ""
+
+	n := New()
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := GetContext(r)
+		SetContext(r, context.WithValue(c, testKey("key"), "the Avengers"))
+		result += GetContext(r).Value(testKey("key")).(string) + ";"
+	})
+	n.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		result += r.Context().Value(testKey("key")).(string) + ";"
+		SetContext(r, context.WithValue(GetContext(r), testKey("other"), "Iron Man"))
+		next(w, r)
+	})
+	n.With(New().WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		result += r.Context().Value(testKey("key")).(string) + "," + r.Context().Value(testKey("other")).(string)
+	}))
+
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expect(t, result, "the Avengers;the Avengers;the Avengers,Iron Man")
+}
+
+func TestSetContextOutsideStack(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+	SetContext(r, context.WithValue(r.Context(), testKey("key"), "value"))
+	expect(t, GetContext(r), r.Context())
+}
+
+func TestSeedContext(t *testing.T) {
+	rec := httptest.NewRecorder()
+	app := context.WithValue(context.Background(), testKey("db"), "app-db")
+	app = context.WithValue(app, testKey("name"), "app")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	req := httptest.NewRequest("GET", "/", nil).WithContext(context.WithValue(ctx, testKey("name"), "request"))
+
+	n := New()
+	n.WithHandlerFunc(SeedContext(app))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		c := GetContext(r)
+		_, hasDeadline := c.Deadline()
+		expect(t, hasDeadline, true)
+		expect(t, c.Value(testKey("db")), "app-db")
+		expect(t, c.Value(testKey("name")), "request")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Code, http.StatusNoContent)
+}
@@ -0,0 +1,82 @@
+package nimble
+
+import (
+	"net"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Matcher reports whether a request should be handled by a conditional middleware.
+type Matcher func(r *http.Request) bool
+
+// PathPrefix matches requests whose URL path starts with the prefix.
+func PathPrefix(prefix string) Matcher {
+	return func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, prefix)
+	}
+}
+
+// PathGlob matches requests whose URL path matches the shell pattern, as used by path.Match.
+// It panics if the pattern is malformed.
+func PathGlob(pattern string) Matcher {
+	if _, err := path.Match(pattern, ""); err != nil {
+		panic("nimble: invalid glob pattern " + pattern)
+	}
+
+	return func(r *http.Request) bool {
+		ok, _ := path.Match(pattern, r.URL.Path)
+		return ok
+	}
+}
+
+// PathRegexp matches requests whose URL path matches the regular expression.
+// It panics if the expression cannot be parsed.
+func PathRegexp(expr string) Matcher {
+	re := regexp.MustCompile(expr)
+
+	return func(r *http.Request) bool {
+		return re.MatchString(r.URL.Path)
+	}
+}
+
+// Method matches requests using any of the methods.
+func Method(methods ...string) Matcher {
+	set := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		set[strings.ToUpper(m)] = true
+	}
+
+	return func(r *http.Request) bool {
+		return set[r.Method]
+	}
+}
+
+// Header matches requests where the header is present.
+func Header(name string) Matcher {
+	name = http.CanonicalHeaderKey(name)
+
+	return func(r *http.Request) bool {
+		_, ok := r.Header[name]
+		return ok
+	}
+}
+
+// Host matches requests addressed to the host, ignoring any port.
+func Host(host string) Matcher {
+	return func(r *http.Request) bool {
+		h := r.Host
+		if hp, _, err := net.SplitHostPort(h); err == nil {
+			h = hp
+		}
+		return strings.EqualFold(h, host)
+	}
+}
+
+// Not matches requests that are not matched by m.
+func Not(m Matcher) Matcher {
+	return func(r *http.Request) bool {
+		return !m(r)
+	}
+}
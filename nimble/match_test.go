@@ -0,0 +1,80 @@
+package nimble
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMatchers(t *testing.T) {
+	req := httptest.NewRequest("POST", "http://api.example.com:8080/api/v1/users.json", nil)
+	req.Header.Set("X-Api-Key", "secret")
+
+	expect(t, PathPrefix("/api")(req), true)
+	expect(t, PathPrefix("/admin")(req), false)
+	expect(t, PathGlob("/api/*/users.*")(req), true)
+	expect(t, PathGlob("/api/*.json")(req), false)
+	expect(t, PathRegexp(`^/api/v[0-9]+/`)(req), true)
+	expect(t, PathRegexp(`^/v[0-9]+/`)(req), false)
+	expect(t, Method("get", "post")(req), true)
+	expect(t, Method("GET")(req), false)
+	expect(t, Header("x-api-key")(req), true)
+	expect(t, Header("Authorization")(req), false)
+	expect(t, Host("API.example.com")(req), true)
+	expect(t, Host("example.com")(req), false)
+	expect(t, Not(Method("GET"))(req), true)
+}
+
+func TestPathGlobInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Expected PathGlob to panic on a malformed pattern")
+		}
+	}()
+
+	PathGlob("[")
+}
+
+func TestNimbleWithIf(t *testing.T) {
+	n := New()
+	n.WithIf(PathPrefix("/api"), func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.Header().Set("X-Api", "true")
+		next(w, r)
+	})
+	n.WithUnless(Method("GET", "HEAD"), func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/api/users", nil))
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("X-Api"), "true")
+
+	rec = httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/home", nil))
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("X-Api"), "")
+
+	rec = httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest("DELETE", "/home", nil))
+	expect(t, rec.Code, http.StatusForbidden)
+	expect(t, rec.Body.String(), "")
+}
+
+func TestNimbleWithIfNilMatcher(t *testing.T) {
+	noop := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {}
+	for _, with := range []func(*Nimble){
+		func(n *Nimble) { n.WithIf(nil, noop) },
+		func(n *Nimble) { n.WithUnless(nil, noop) },
+	} {
+		func() {
+			defer func() {
+				expect(t, recover(), "matcher cannot be nil")
+			}()
+			with(New())
+		}()
+	}
+}
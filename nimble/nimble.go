@@ -37,21 +37,59 @@ func New() *Nimble {
 
 // Nimble itself is a http.Handler. This allows it to used as a substack manager
 func (n *Nimble) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = withState(r)
 	if _, ok := w.(Writer); ok { // handle substacks
 		n.middleware.serve(w, r)
-	} else {
-		n.middleware.serve(newWriter(w), r)
+		return
 	}
+
+	ww := newWriter(w)
+	defer callAfter(ww)
+	n.middleware.serve(ww, r)
 }
 
 // With adds a http.Handler onto the middleware stack.
+// The rest of the stack is skipped if the handler writes to the response.
 func (n *Nimble) With(handler http.Handler) *Nimble {
-	return n.WithHandlerFunc(wrap(handler))
+	return n.WithHandlerFunc(wrap(handler, false))
 }
 
 // WithFunc adds a http.HandlerFunc onto the middleware stack.
+// The rest of the stack is skipped if the handler writes to the response.
 func (n *Nimble) WithFunc(handlerFunc http.HandlerFunc) *Nimble {
-	return n.WithHandlerFunc(wrapHandlerFunc(handlerFunc))
+	return n.WithHandlerFunc(wrapHandlerFunc(handlerFunc, false))
+}
+
+// WithContinue adds a http.Handler onto the middleware stack. Unlike With,
+// the rest of the stack is always invoked, even if the handler has written to the response.
+func (n *Nimble) WithContinue(handler http.Handler) *Nimble {
+	return n.WithHandlerFunc(wrap(handler, true))
+}
+
+// WithFuncContinue adds a http.HandlerFunc onto the middleware stack. Unlike WithFunc,
+// the rest of the stack is always invoked, even if the handler has written to the response.
+func (n *Nimble) WithFuncContinue(handlerFunc http.HandlerFunc) *Nimble {
+	return n.WithHandlerFunc(wrapHandlerFunc(handlerFunc, true))
+}
+
+// WithRequestFunc adds a function onto the middleware stack that may return a derived
+// request, such as one carrying new context values or a rewritten URL. The returned request
+// is passed to the rest of the stack; if it is nil, the original request is passed instead.
+// The rest of the stack is skipped if the function writes to the response.
+func (n *Nimble) WithRequestFunc(fn func(w http.ResponseWriter, r *http.Request) *http.Request) *Nimble {
+	if fn == nil {
+		panic("handlerFunc cannot be nil")
+	}
+
+	return n.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if r2 := fn(w, r); r2 != nil {
+			r = r2
+		}
+		if written(w) {
+			return
+		}
+		next(w, r)
+	})
 }
 
 // WithHandler adds a nimble.Handler onto the middleware stack.
@@ -70,36 +108,83 @@ func (n *Nimble) WithHandlerFunc(handlerFunc HandlerFunc) *Nimble {
 	return n
 }
 
+// WithIf adds a nimble.HandlerFunc onto the middleware stack that only runs
+// for requests matched by the matcher. Other requests skip straight to the next middleware.
+func (n *Nimble) WithIf(matcher Matcher, handlerFunc HandlerFunc) *Nimble {
+	if matcher == nil {
+		panic("matcher cannot be nil")
+	}
+	return n.WithHandlerFunc(conditional(matcher, handlerFunc, true))
+}
+
+// WithUnless adds a nimble.HandlerFunc onto the middleware stack that only runs
+// for requests not matched by the matcher. Other requests skip straight to the next middleware.
+func (n *Nimble) WithUnless(matcher Matcher, handlerFunc HandlerFunc) *Nimble {
+	if matcher == nil {
+		panic("matcher cannot be nil")
+	}
+	return n.WithHandlerFunc(conditional(matcher, handlerFunc, false))
+}
+
 // The next http.HandlerFunc is automatically called after the Handler is executed.
 // If the Handler writes to the ResponseWriter, the next http.HandlerFunc should not be invoked.
+// A context set with SetContext by the previous Handler is passed on with the request.
+// The request is recorded on the Writer while the Handler runs, so functions it adds with After receive it.
 func (m *middleware) serve(w http.ResponseWriter, r *http.Request) {
+	r = withPending(r)
+	if ww := baseWriter(w); ww != nil {
+		defer func(prev *http.Request) { ww.request = prev }(ww.request)
+		ww.request = r
+	}
 	m.fn(w, r, m.next.serve)
 }
 
 // Wrap converts a http.Handler into a nimble.HandlerFunc
-func wrap(handler http.Handler) HandlerFunc {
+func wrap(handler http.Handler, always bool) HandlerFunc {
 	if handler == nil {
 		return nil
 	}
 
-	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
-		handler.ServeHTTP(w, r)
-		next(w, r)
-	}
+	return wrapHandlerFunc(handler.ServeHTTP, always)
 }
 
 // wrapFunc converts a http.HandlerFunc into a nimble.HandlerFunc.
-func wrapHandlerFunc(fn http.HandlerFunc) HandlerFunc {
+// Unless always is set, next is only invoked if fn did not write to the response.
+func wrapHandlerFunc(fn http.HandlerFunc, always bool) HandlerFunc {
 	if fn == nil {
 		return nil
 	}
 
 	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 		fn(w, r)
+		if !always && written(w) {
+			return
+		}
 		next(w, r)
 	}
 }
 
+// written reports whether the response has already been written to.
+func written(w http.ResponseWriter) bool {
+	ww, ok := w.(Writer)
+	return ok && ww.Written()
+}
+
+// conditional runs fn only when the matcher result equals want.
+func conditional(matcher Matcher, fn HandlerFunc, want bool) HandlerFunc {
+	if fn == nil {
+		return nil
+	}
+
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if matcher(r) != want {
+			next(w, r)
+			return
+		}
+		fn(w, r, next)
+	}
+}
+
 func build(handles []HandlerFunc) middleware {
 	var next middleware
 
@@ -1,9 +1,12 @@
 package nimble
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -81,3 +84,176 @@ func TestNimbleUseNil(t *testing.T) {
 	n := New()
 	n.With(nil)
 }
+
+func TestNimbleWithShortCircuits(t *testing.T) {
+	rec := httptest.NewRecorder()
+	reached := false
+
+	n := New()
+	n.With(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.Write([]byte("router"))
+	})
+
+	n.ServeHTTP(rec, (*http.Request)(nil))
+
+	expect(t, reached, false)
+	expect(t, rec.Code, http.StatusUnauthorized)
+	expect(t, rec.Body.String(), "unauthorized\n")
+}
+
+func TestNimbleWithFuncPassesThroughWhenNotWritten(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	n := New()
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Stage", "first")
+	})
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("second"))
+	})
+
+	n.ServeHTTP(rec, (*http.Request)(nil))
+
+	expect(t, rec.Header().Get("X-Stage"), "first")
+	expect(t, rec.Body.String(), "second")
+}
+
+func TestNimbleWithContinue(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	n := New()
+	n.WithContinue(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("header;"))
+	}))
+	n.WithFuncContinue(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body;"))
+	})
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("footer"))
+	})
+
+	n.ServeHTTP(rec, (*http.Request)(nil))
+
+	expect(t, rec.Body.String(), "header;body;footer")
+}
+
+func TestNimbleWithRequestFunc(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	n := New()
+	n.WithRequestFunc(func(w http.ResponseWriter, r *http.Request) *http.Request {
+		r2 := r.WithContext(context.WithValue(r.Context(), testKey("principal"), "tony"))
+		u := *r.URL
+		u.Path = strings.TrimPrefix(u.Path, "/api")
+		r2.URL = &u
+		return r2
+	})
+	n.WithRequestFunc(func(w http.ResponseWriter, r *http.Request) *http.Request {
+		return nil
+	})
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Context().Value(testKey("principal")).(string) + " " + r.URL.Path))
+	})
+
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/api/users", nil))
+	expect(t, rec.Body.String(), "tony /users")
+}
+
+func TestNimbleWithRequestFuncShortCircuits(t *testing.T) {
+	rec := httptest.NewRecorder()
+	reached := false
+
+	n := New()
+	n.WithRequestFunc(func(w http.ResponseWriter, r *http.Request) *http.Request {
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil
+	})
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	})
+
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expect(t, rec.Code, http.StatusUnauthorized)
+	expect(t, reached, false)
+}
+
+func TestNimbleAfterRunsOnce(t *testing.T) {
+	var calls []string
+	n := New()
+	n.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.(Writer).After(func(w Writer, r *http.Request) {
+			calls = append(calls, fmt.Sprintf("first %s %v", r.URL.Path, r.Context().Value(testKey("user"))))
+		})
+		next(w, r)
+	})
+	n.WithRequestFunc(func(w http.ResponseWriter, r *http.Request) *http.Request {
+		return r.WithContext(context.WithValue(r.Context(), testKey("user"), "gopher"))
+	})
+	sub := New()
+	sub.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.(Writer).After(func(w Writer, r *http.Request) {
+			calls = append(calls, fmt.Sprintf("second %s %v", http.StatusText(w.Status()), r.Context().Value(testKey("user"))))
+		})
+		next(w, r)
+	})
+	n.WithContinue(sub)
+	n.With(http.NotFoundHandler())
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("short-circuited handler should not run")
+	})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/foo", nil))
+	expect(t, rec.Code, http.StatusNotFound)
+	expect(t, strings.Join(calls, ", "), "second Not Found gopher, first /foo <nil>")
+}
+
+func TestNimbleAfterRequestThroughWrapper(t *testing.T) {
+	path := ""
+	n := New()
+	n.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		bw := Buffer(w, 1024)
+		defer bw.Commit()
+		next(bw, r)
+	})
+	n.WithRequestFunc(func(w http.ResponseWriter, r *http.Request) *http.Request {
+		r2 := r.Clone(r.Context())
+		r2.URL.Path = "/rewritten"
+		return r2
+	})
+	n.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.(Writer).After(func(w Writer, r *http.Request) {
+			path = r.URL.Path
+		})
+		next(w, r)
+	})
+
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/foo", nil))
+	expect(t, path, "/rewritten")
+}
+
+func TestNimbleAfterRunsOnPanic(t *testing.T) {
+	status := -1
+	n := New()
+	n.WithHandlerFunc(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		w.(Writer).After(func(w Writer, r *http.Request) {
+			status = w.Status()
+		})
+		next(w, r)
+	})
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	func() {
+		defer func() {
+			expect(t, recover(), "boom")
+		}()
+		n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	expect(t, status, 0)
+}
@@ -0,0 +1,37 @@
+package router
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const paramsKey contextKey = iota
+
+// Parameter is a named value captured from the request path.
+type Parameter struct {
+	Name  string
+	Value string
+}
+
+// Param returns the value of the named route parameter, or "" if there is none.
+// The trailing wildcard of a pattern such as /files/* is named "*".
+func Param(r *http.Request, name string) string {
+	for _, p := range Params(r) {
+		if p.Name == name {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+// Params returns the route parameters of the request in the order they appear in the pattern.
+func Params(r *http.Request) []Parameter {
+	params, _ := r.Context().Value(paramsKey).([]Parameter)
+	return params
+}
+
+func withParams(r *http.Request, params []Parameter) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), paramsKey, params))
+}
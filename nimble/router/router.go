@@ -0,0 +1,269 @@
+// Package router is a lightweight request router that plugs into a nimble stack.
+//
+//	r := router.New()
+//	r.Get("/articles/{id}", showArticle, requireAuth)
+//	r.Get("/assets/{path...}", serveAsset)
+//
+//	n := nim.Default()
+//	n.With(r)
+//
+// Route parameters are read with router.Param(r, "id").
+package router
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+// Router matches requests against registered method and path patterns.
+//
+// Patterns are made up of literal segments, named parameters such as {id}
+// that match a single segment, and a trailing wildcard {name...} (or *)
+// that matches the rest of the path. Literal segments take precedence over
+// parameters, and parameters over wildcards.
+type Router struct {
+	routes []*route
+	// NotFound handles requests that match no route. Defaults to http.NotFound.
+	NotFound http.Handler
+	// MethodNotAllowed handles requests that match a route pattern but not its method.
+	// The Allow header is set before it is called. Defaults to a plain 405 response.
+	MethodNotAllowed http.Handler
+}
+
+type route struct {
+	method   string
+	segments []segment
+	handler  http.Handler
+}
+
+type segmentKind int
+
+const (
+	literal segmentKind = iota
+	param
+	wildcard
+)
+
+type segment struct {
+	kind  segmentKind
+	value string
+}
+
+// Make sure Router conforms with the http.Handler interface
+var _ http.Handler = New()
+
+// New returns a new Router with no routes.
+func New() *Router {
+	return &Router{
+		NotFound:         http.HandlerFunc(http.NotFound),
+		MethodNotAllowed: http.HandlerFunc(methodNotAllowed),
+	}
+}
+
+// Handle registers the handler for the method and pattern. The middleware is run
+// in sequence before the handler, in the same manner as a nimble stack.
+// It panics if the pattern is malformed.
+func (rt *Router) Handle(method, pattern string, handler http.Handler, middleware ...nimble.HandlerFunc) {
+	if handler == nil {
+		panic("router: handler cannot be nil")
+	}
+
+	if len(middleware) > 0 {
+		stack := nimble.New()
+		for _, mw := range middleware {
+			stack.WithHandlerFunc(mw)
+		}
+		handler = stack.With(handler)
+	}
+
+	rt.routes = append(rt.routes, &route{
+		method:   strings.ToUpper(method),
+		segments: parse(pattern),
+		handler:  handler,
+	})
+}
+
+// HandleFunc registers the handler function for the method and pattern.
+func (rt *Router) HandleFunc(method, pattern string, fn http.HandlerFunc, middleware ...nimble.HandlerFunc) {
+	rt.Handle(method, pattern, fn, middleware...)
+}
+
+// Get registers the handler function for GET requests. HEAD requests are also served
+// unless a HEAD route is registered for the same pattern.
+func (rt *Router) Get(pattern string, fn http.HandlerFunc, middleware ...nimble.HandlerFunc) {
+	rt.HandleFunc(http.MethodGet, pattern, fn, middleware...)
+}
+
+// Post registers the handler function for POST requests.
+func (rt *Router) Post(pattern string, fn http.HandlerFunc, middleware ...nimble.HandlerFunc) {
+	rt.HandleFunc(http.MethodPost, pattern, fn, middleware...)
+}
+
+// Put registers the handler function for PUT requests.
+func (rt *Router) Put(pattern string, fn http.HandlerFunc, middleware ...nimble.HandlerFunc) {
+	rt.HandleFunc(http.MethodPut, pattern, fn, middleware...)
+}
+
+// Patch registers the handler function for PATCH requests.
+func (rt *Router) Patch(pattern string, fn http.HandlerFunc, middleware ...nimble.HandlerFunc) {
+	rt.HandleFunc(http.MethodPatch, pattern, fn, middleware...)
+}
+
+// Delete registers the handler function for DELETE requests.
+func (rt *Router) Delete(pattern string, fn http.HandlerFunc, middleware ...nimble.HandlerFunc) {
+	rt.HandleFunc(http.MethodDelete, pattern, fn, middleware...)
+}
+
+func (rt *Router) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := split(r.URL.Path)
+
+	var (
+		best      *route
+		bestScore []segmentKind
+		bestVals  []string
+		allowed   = map[string]bool{}
+	)
+	for _, rte := range rt.routes {
+		values, ok := rte.match(path)
+		if !ok {
+			continue
+		}
+		allowed[rte.method] = true
+		if rte.method == http.MethodGet {
+			allowed[http.MethodHead] = true
+		}
+
+		if !rte.accepts(r.Method) {
+			continue
+		}
+		score := rte.score()
+		if best == nil || better(score, bestScore) ||
+			(equal(score, bestScore) && best.method != r.Method && rte.method == r.Method) {
+			best, bestScore, bestVals = rte, score, values
+		}
+	}
+
+	if best == nil {
+		if len(allowed) == 0 {
+			rt.NotFound.ServeHTTP(w, r)
+			return
+		}
+		methods := make([]string, 0, len(allowed))
+		for m := range allowed {
+			methods = append(methods, m)
+		}
+		sort.Strings(methods)
+		w.Header().Set("Allow", strings.Join(methods, ", "))
+		rt.MethodNotAllowed.ServeHTTP(w, r)
+		return
+	}
+
+	if len(bestVals) > 0 {
+		r = withParams(r, best.params(bestVals))
+	}
+	best.handler.ServeHTTP(w, r)
+}
+
+// accepts reports whether the route serves the request method.
+func (rte *route) accepts(method string) bool {
+	return rte.method == method || (rte.method == http.MethodGet && method == http.MethodHead)
+}
+
+// match returns the values captured by the parameters of the route.
+func (rte *route) match(path []string) ([]string, bool) {
+	var values []string
+	for i, seg := range rte.segments {
+		if seg.kind == wildcard {
+			return append(values, strings.Join(path[i:], "/")), true
+		}
+		if i >= len(path) {
+			return nil, false
+		}
+		switch seg.kind {
+		case literal:
+			if seg.value != path[i] {
+				return nil, false
+			}
+		case param:
+			if path[i] == "" {
+				return nil, false
+			}
+			values = append(values, path[i])
+		}
+	}
+	if len(path) != len(rte.segments) {
+		return nil, false
+	}
+	return values, true
+}
+
+func (rte *route) params(values []string) []Parameter {
+	params := make([]Parameter, 0, len(values))
+	for _, seg := range rte.segments {
+		if seg.kind != literal {
+			params = append(params, Parameter{seg.value, values[len(params)]})
+		}
+	}
+	return params
+}
+
+func (rte *route) score() []segmentKind {
+	score := make([]segmentKind, len(rte.segments))
+	for i, seg := range rte.segments {
+		score[i] = seg.kind
+	}
+	return score
+}
+
+// better reports whether a is more specific than b, comparing segments from left to right.
+func better(a, b []segmentKind) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) > len(b)
+}
+
+func equal(a, b []segmentKind) bool {
+	return !better(a, b) && !better(b, a)
+}
+
+func parse(pattern string) []segment {
+	if !strings.HasPrefix(pattern, "/") {
+		panic("router: pattern must begin with '/' in " + pattern)
+	}
+
+	parts := split(pattern)
+	segments := make([]segment, len(parts))
+	for i, part := range parts {
+		last := i == len(parts)-1
+		switch {
+		case part == "*" && last:
+			segments[i] = segment{wildcard, "*"}
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "...}") && last:
+			segments[i] = segment{wildcard, part[1 : len(part)-4]}
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}"):
+			segments[i] = segment{param, part[1 : len(part)-1]}
+		case strings.ContainsAny(part, "{}*"):
+			panic("router: malformed segment " + part + " in " + pattern)
+		default:
+			segments[i] = segment{literal, part}
+		}
+		if segments[i].kind != literal && (segments[i].value == "" || strings.ContainsAny(segments[i].value, "{}/.")) {
+			panic("router: malformed segment " + part + " in " + pattern)
+		}
+	}
+	return segments
+}
+
+func split(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+}
@@ -0,0 +1,132 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+/* Test Helpers */
+func expect(t *testing.T, a interface{}, b interface{}) {
+	if a != b {
+		t.Errorf("Expected %v (type %v) - Got %v (type %v)", b, reflect.TypeOf(b), a, reflect.TypeOf(a))
+	}
+}
+
+func serve(h http.Handler, method, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+	return rec
+}
+
+func echo(names ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for _, name := range names {
+			w.Write([]byte(name + "=" + Param(r, name) + ";"))
+		}
+	}
+}
+
+func TestRouterParams(t *testing.T) {
+	rt := New()
+	rt.Get("/articles/{category}/{id}", echo("category", "id"))
+	rt.Get("/files/{path...}", echo("path"))
+	rt.Get("/static/*", echo("*"))
+
+	expect(t, serve(rt, "GET", "/articles/go/42").Body.String(), "category=go;id=42;")
+	expect(t, serve(rt, "GET", "/files/css/site.css").Body.String(), "path=css/site.css;")
+	expect(t, serve(rt, "GET", "/static/js/app.js").Body.String(), "*=js/app.js;")
+	expect(t, serve(rt, "GET", "/articles/go").Code, http.StatusNotFound)
+	expect(t, serve(rt, "GET", "/articles/go/42/comments").Code, http.StatusNotFound)
+}
+
+func TestRouterPrecedence(t *testing.T) {
+	rt := New()
+	rt.Get("/users/{id}", echo("id"))
+	rt.Get("/users/me", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("me"))
+	})
+	rt.Get("/users/*", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("wildcard"))
+	})
+
+	expect(t, serve(rt, "GET", "/users/me").Body.String(), "me")
+	expect(t, serve(rt, "GET", "/users/7").Body.String(), "id=7;")
+	expect(t, serve(rt, "GET", "/users/7/posts").Body.String(), "wildcard")
+}
+
+func TestRouterMethodNotAllowed(t *testing.T) {
+	rt := New()
+	rt.Get("/articles", echo())
+	rt.Post("/articles", echo())
+	rt.Delete("/articles/{id}", echo())
+
+	rec := serve(rt, "PUT", "/articles")
+	expect(t, rec.Code, http.StatusMethodNotAllowed)
+	expect(t, rec.Header().Get("Allow"), "GET, HEAD, POST")
+
+	expect(t, serve(rt, "HEAD", "/articles").Code, http.StatusOK)
+	expect(t, serve(rt, "GET", "/articles/1").Header().Get("Allow"), "DELETE")
+	expect(t, serve(rt, "GET", "/missing").Code, http.StatusNotFound)
+}
+
+func TestRouterCustomNotFound(t *testing.T) {
+	rt := New()
+	rt.NotFound = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	expect(t, serve(rt, "GET", "/").Code, http.StatusTeapot)
+}
+
+func TestRouterMiddleware(t *testing.T) {
+	result := ""
+	auth := func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		result += "auth;"
+		if r.Header.Get("Authorization") == "" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+
+	rt := New()
+	rt.Get("/private/{id}", func(w http.ResponseWriter, r *http.Request) {
+		result += "handler=" + Param(r, "id") + ";"
+	}, auth)
+	rt.Get("/public", func(w http.ResponseWriter, r *http.Request) {
+		result += "public;"
+	})
+
+	n := nimble.New().With(rt)
+
+	expect(t, serve(n, "GET", "/private/1").Code, http.StatusUnauthorized)
+	expect(t, result, "auth;")
+
+	result = ""
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/private/1", nil)
+	req.Header.Set("Authorization", "token")
+	n.ServeHTTP(rec, req)
+	expect(t, result, "auth;handler=1;")
+
+	result = ""
+	serve(n, "GET", "/public")
+	expect(t, result, "public;")
+}
+
+func TestRouterMalformedPattern(t *testing.T) {
+	for _, pattern := range []string{"articles", "/files/*/x", "/a/{}", "/a/{id", "/a/{path...}/b"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected pattern %q to panic", pattern)
+				}
+			}()
+			New().Get(pattern, echo())
+		}()
+	}
+}
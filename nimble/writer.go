@@ -3,8 +3,10 @@ package nimble
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"time"
 )
 
 // Writer is the interface response wrapper that provides extra information about
@@ -22,6 +24,22 @@ type Writer interface {
 	// Before allows for a function to be called before the ResponseWriter has been written to. This is
 	// useful for setting headers or any other operations that must happen before a response has been written.
 	Before(func(Writer))
+	// After allows for a function to be called once the whole middleware chain has returned,
+	// including when a handler panics, short-circuits the chain or hijacks the connection.
+	// Functions run exactly once, in the reverse order they were added, which makes them
+	// suited to metrics, audit logging and cleanup. Functions receive the request passed to
+	// the middleware that added them, which carries the context set so far, or nil when the
+	// Writer is used outside a Nimble stack.
+	After(func(Writer, *http.Request))
+	// HeaderTime returns when the status and headers were written, or the zero time if they have not been.
+	HeaderTime() time.Time
+	// FirstByte returns when the first byte of the body was written, or the zero time if none has been.
+	FirstByte() time.Time
+	// LastWrite returns when the body was last written to, or the zero time if it has not been.
+	LastWrite() time.Time
+	// Hijacked returns whether the connection has been hijacked, after which the status is reported
+	// as 101 Switching Protocols unless one was written, and writes fail with http.ErrHijacked.
+	Hijacked() bool
 }
 
 // ResponseWriter is a light wrapper around http.ResponseWriter that provides extra information about
@@ -32,6 +50,13 @@ type writer struct {
 	status      int
 	size        int
 	beforeFuncs []beforeFunc
+	afterFuncs  []afterFunc
+	afterCalled bool
+	request     *http.Request
+	headerTime  time.Time
+	firstByte   time.Time
+	lastWrite   time.Time
+	hijacked    bool
 }
 
 type writerCloseNotifer struct {
@@ -40,6 +65,11 @@ type writerCloseNotifer struct {
 
 type beforeFunc func(Writer)
 
+type afterFunc struct {
+	fn func(Writer, *http.Request)
+	r  *http.Request
+}
+
 // newWriter creates a Writer that wraps an http.ResponseWriter
 func newWriter(w http.ResponseWriter) Writer {
 	nw := &writer{
@@ -55,21 +85,69 @@ func newWriter(w http.ResponseWriter) Writer {
 }
 
 func (w *writer) WriteHeader(s int) {
+	if w.hijacked {
+		return
+	}
+	if w.headerTime.IsZero() {
+		w.headerTime = time.Now()
+	}
 	w.status = s
 	w.callBefore()
 	w.ResponseWriter.WriteHeader(s)
 }
 
 func (w *writer) Write(b []byte) (int, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
 	if !w.Written() {
 		// The status will be StatusOK if WriteHeader has not been called yet
 		w.WriteHeader(http.StatusOK)
 	}
 	size, err := w.ResponseWriter.Write(b)
-	w.size += size
+	w.wrote(size)
 	return size, err
 }
 
+// wrote records the timing and size of a write of n bytes to the body.
+func (w *writer) wrote(n int) {
+	if n == 0 {
+		return
+	}
+	now := time.Now()
+	if w.firstByte.IsZero() {
+		w.firstByte = now
+	}
+	w.lastWrite = now
+	w.size += n
+}
+
+// ReadFrom copies from r using the io.ReaderFrom of the underlying ResponseWriter
+// when it has one, which lets files be sent without copying them through user space.
+func (w *writer) ReadFrom(r io.Reader) (int64, error) {
+	if w.hijacked {
+		return 0, http.ErrHijacked
+	}
+	if !w.Written() {
+		// The status will be StatusOK if WriteHeader has not been called yet
+		w.WriteHeader(http.StatusOK)
+	}
+	var n int64
+	var err error
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err = rf.ReadFrom(r)
+	} else {
+		n, err = io.Copy(writerOnly{w.ResponseWriter}, r)
+	}
+	w.wrote(int(n))
+	return n, err
+}
+
+// writerOnly hides the optional interfaces of a writer, so io.Copy does not loop back to ReadFrom.
+type writerOnly struct {
+	io.Writer
+}
+
 func (w *writer) Status() int {
 	return w.status
 }
@@ -82,16 +160,60 @@ func (w *writer) Written() bool {
 	return w.status != 0
 }
 
+func (w *writer) HeaderTime() time.Time {
+	return w.headerTime
+}
+
+func (w *writer) FirstByte() time.Time {
+	return w.firstByte
+}
+
+func (w *writer) LastWrite() time.Time {
+	return w.lastWrite
+}
+
 func (w *writer) Before(before func(Writer)) {
 	w.beforeFuncs = append(w.beforeFuncs, before)
 }
 
+func (w *writer) After(after func(Writer, *http.Request)) {
+	w.afterFuncs = append(w.afterFuncs, afterFunc{after, w.request})
+}
+
 func (w *writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	hijacker, ok := w.ResponseWriter.(http.Hijacker)
 	if !ok {
 		return nil, nil, fmt.Errorf("the ResponseWriter doesn't support the http.Hijack interface")
 	}
-	return hijacker.Hijack()
+	conn, rw, err := hijacker.Hijack()
+	if err == nil {
+		w.hijacked = true
+		if w.status == 0 {
+			// the handler takes over the connection to switch protocols, as for a WebSocket upgrade
+			w.status = http.StatusSwitchingProtocols
+		}
+	}
+	return conn, rw, err
+}
+
+func (w *writer) Hijacked() bool {
+	return w.hijacked
+}
+
+// Push initiates an HTTP/2 server push, or returns http.ErrNotSupported if the
+// underlying ResponseWriter does not support it.
+func (w *writer) Push(target string, opts *http.PushOptions) error {
+	pusher, ok := w.ResponseWriter.(http.Pusher)
+	if !ok {
+		return http.ErrNotSupported
+	}
+	return pusher.Push(target, opts)
+}
+
+// Unwrap returns the underlying ResponseWriter, which lets http.ResponseController
+// reach features such as read and write deadlines and full-duplex mode.
+func (w *writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
 
 func (w *writer) callBefore() {
@@ -100,9 +222,20 @@ func (w *writer) callBefore() {
 	}
 }
 
+// callAfter runs the after functions, unless they have already run.
+func (w *writer) callAfter(ww Writer) {
+	if w.afterCalled {
+		return
+	}
+	w.afterCalled = true
+	for i := len(w.afterFuncs) - 1; i >= 0; i-- {
+		w.afterFuncs[i].fn(ww, w.afterFuncs[i].r)
+	}
+}
+
 func (w *writer) Flush() {
 	flusher, ok := w.ResponseWriter.(http.Flusher)
-	if ok {
+	if ok && !w.hijacked {
 		if !w.Written() {
 			// The status will be StatusOK if WriteHeader has not been called yet
 			w.WriteHeader(http.StatusOK)
@@ -111,6 +244,32 @@ func (w *writer) Flush() {
 	}
 }
 
+// callAfter runs the after functions of a Writer created by newWriter.
+func callAfter(w Writer) {
+	switch ww := w.(type) {
+	case *writer:
+		ww.callAfter(w)
+	case *writerCloseNotifer:
+		ww.callAfter(w)
+	}
+}
+
+// baseWriter returns the writer created by newWriter that w wraps, or nil if there is none.
+func baseWriter(w http.ResponseWriter) *writer {
+	for {
+		switch ww := w.(type) {
+		case *writer:
+			return ww
+		case *writerCloseNotifer:
+			return ww.writer
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = ww.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
 // CloseNotify provides notifications when the HTTP connection terminates
 func (wcn *writerCloseNotifer) CloseNotify() <-chan bool {
 	return wcn.ResponseWriter.(http.CloseNotifier).CloseNotify()
@@ -2,8 +2,10 @@ package nimble
 
 import (
 	"bufio"
+	"io"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"net/http/httptest"
@@ -165,3 +167,154 @@ func TestResponseWriteHijackNotOK(t *testing.T) {
 
 	refute(t, err, nil)
 }
+
+type readerFromRecorder struct {
+	*httptest.ResponseRecorder
+	readFrom bool
+}
+
+func (rf *readerFromRecorder) ReadFrom(r io.Reader) (int64, error) {
+	rf.readFrom = true
+	return io.Copy(rf.ResponseRecorder, r)
+}
+
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (p *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	p.pushed = append(p.pushed, target)
+	return nil
+}
+
+func TestResponseWriterReadFrom(t *testing.T) {
+	rec := &readerFromRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w := newWriter(rec)
+
+	n, err := io.Copy(w, io.LimitReader(strings.NewReader("Hello world"), 64))
+	expect(t, err, nil)
+	expect(t, n, int64(11))
+	expect(t, rec.readFrom, true)
+	expect(t, rec.Body.String(), "Hello world")
+	expect(t, w.Status(), http.StatusOK)
+	expect(t, w.Size(), 11)
+}
+
+func TestResponseWriterReadFromFallback(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newWriter(rec)
+	w.WriteHeader(http.StatusCreated)
+
+	n, err := w.(io.ReaderFrom).ReadFrom(strings.NewReader("Hello world"))
+	expect(t, err, nil)
+	expect(t, n, int64(11))
+	expect(t, rec.Code, http.StatusCreated)
+	expect(t, rec.Body.String(), "Hello world")
+	expect(t, w.Size(), 11)
+}
+
+func TestResponseWriterPush(t *testing.T) {
+	rec := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w := newWriter(rec)
+
+	err := w.(http.Pusher).Push("/app.css", nil)
+	expect(t, err, nil)
+	expect(t, len(rec.pushed), 1)
+
+	w = newWriter(httptest.NewRecorder())
+	expect(t, w.(http.Pusher).Push("/app.css", nil), http.ErrNotSupported)
+}
+
+func TestResponseWriterUnwrap(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := newWriter(rec)
+	expect(t, w.(interface{ Unwrap() http.ResponseWriter }).Unwrap(), http.ResponseWriter(rec))
+
+	var deadlineErr error
+	n := New()
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		deadlineErr = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(time.Minute))
+	})
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	expect(t, deadlineErr, nil)
+}
+
+func TestResponseWriterAfter(t *testing.T) {
+	w := newWriter(httptest.NewRecorder())
+	result := ""
+
+	w.After(func(w Writer, r *http.Request) {
+		expect(t, r, (*http.Request)(nil))
+		result += "foo"
+	})
+	w.After(func(w Writer, r *http.Request) {
+		result += "bar"
+	})
+
+	callAfter(w)
+	callAfter(w)
+	expect(t, result, "barfoo")
+}
+
+func TestResponseWriterTiming(t *testing.T) {
+	w := newWriter(httptest.NewRecorder())
+	expect(t, w.HeaderTime().IsZero(), true)
+	expect(t, w.FirstByte().IsZero(), true)
+	expect(t, w.LastWrite().IsZero(), true)
+
+	w.WriteHeader(http.StatusOK)
+	header := w.HeaderTime()
+	expect(t, header.IsZero(), false)
+	expect(t, w.FirstByte().IsZero(), true)
+
+	time.Sleep(time.Millisecond)
+	w.Write([]byte("Hello"))
+	first := w.FirstByte()
+	expect(t, first.After(header), true)
+
+	time.Sleep(time.Millisecond)
+	w.Write([]byte(" world"))
+	expect(t, w.FirstByte(), first)
+	expect(t, w.LastWrite().After(first), true)
+	expect(t, w.HeaderTime(), header)
+}
+
+func TestResponseWriterHijackTracking(t *testing.T) {
+	hijackable := newHijackableResponse()
+	w := newWriter(hijackable)
+	before := false
+	w.Before(func(Writer) {
+		before = true
+	})
+	expect(t, w.Hijacked(), false)
+
+	w.(http.Hijacker).Hijack()
+	expect(t, w.Hijacked(), true)
+	expect(t, w.Status(), http.StatusSwitchingProtocols)
+	expect(t, w.Written(), true)
+
+	w.WriteHeader(http.StatusInternalServerError)
+	expect(t, w.Status(), http.StatusSwitchingProtocols)
+	_, err := w.Write([]byte("Hello"))
+	expect(t, err, http.ErrHijacked)
+	_, err = w.(io.ReaderFrom).ReadFrom(strings.NewReader("Hello"))
+	expect(t, err, http.ErrHijacked)
+	expect(t, w.Size(), 0)
+	expect(t, before, false)
+}
+
+func TestResponseWriteHijackFailed(t *testing.T) {
+	w := newWriter(httptest.NewRecorder())
+	_, _, err := w.(http.Hijacker).Hijack()
+	refute(t, err, nil)
+	expect(t, w.Hijacked(), false)
+	expect(t, w.Status(), 0)
+}
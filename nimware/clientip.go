@@ -0,0 +1,166 @@
+package nimware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+type clientIPKey struct{}
+
+// IPResolver is a middleware that resolves the IP address of the client and stores it
+// in the request context, where it is available to later middleware with ClientIP.
+// It should be placed before the Logger in the stack.
+//
+// A single forwarding header, X-Forwarded-For by default, is read and only when the request
+// comes from a trusted proxy. Otherwise the peer address is used. Other forwarding headers
+// are ignored, since a proxy that does not set them passes on whatever the client sent.
+type IPResolver struct {
+	trusted []*net.IPNet
+	header  string
+}
+
+// IPResolverOption configures an IPResolver.
+type IPResolverOption func(*IPResolver)
+
+// IPResolverHeader sets the forwarding header that the trusted proxies set, such as
+// "X-Forwarded-For", "X-Real-Ip" or "Forwarded" (RFC 7239). Headers other than
+// Forwarded are read as a comma-separated list of addresses.
+func IPResolverHeader(header string) IPResolverOption {
+	return func(res *IPResolver) {
+		res.header = http.CanonicalHeaderKey(header)
+	}
+}
+
+// NewIPResolver returns a new IPResolver that trusts the proxies, given as CIDR
+// ranges such as "10.0.0.0/8" or as single IP addresses.
+func NewIPResolver(trustedProxies []string, opts ...IPResolverOption) (*IPResolver, error) {
+	res := &IPResolver{header: "X-Forwarded-For"}
+	for _, opt := range opts {
+		opt(res)
+	}
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		_, ipnet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, err
+		}
+		res.trusted = append(res.trusted, ipnet)
+	}
+	return res, nil
+}
+
+func (res *IPResolver) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ip := res.Resolve(r)
+	next(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+}
+
+// Resolve returns the IP address of the client that sent the request.
+func (res *IPResolver) Resolve(r *http.Request) string {
+	peer := remoteIP(r)
+	if !res.isTrusted(peer) {
+		return peer
+	}
+
+	var hops []string
+	if res.header == "Forwarded" {
+		hops = forwardedFor(r.Header.Values(res.header))
+	} else {
+		hops = xForwardedFor(r.Header.Values(res.header))
+	}
+	return res.client(hops, peer)
+}
+
+// client walks the hops from the nearest proxy outward and returns the first untrusted address.
+// A hop that is not an IP address ends the walk at the proxy that reported it.
+func (res *IPResolver) client(hops []string, peer string) string {
+	ip := peer
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i] == "" {
+			return ip
+		}
+		ip = hops[i]
+		if !res.isTrusted(ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+func (res *IPResolver) isTrusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, ipnet := range res.trusted {
+		if ipnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP address of the client resolved by an IPResolver earlier in the
+// stack, or the peer address of the request if there is none.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+// remoteIP returns the IP address of the peer that sent the request.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func xForwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, hop := range strings.Split(value, ",") {
+			hops = append(hops, parseIP(hop))
+		}
+	}
+	return hops
+}
+
+func forwardedFor(values []string) []string {
+	var hops []string
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "for") {
+					continue
+				}
+				hops = append(hops, parseIP(strings.Trim(kv[1], `"`)))
+			}
+		}
+	}
+	return hops
+}
+
+// parseIP returns the IP address in s, which may carry a port and IPv6 brackets,
+// or "" if s is not an IP address.
+func parseIP(s string) string {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if ip := net.ParseIP(s); ip != nil {
+		return ip.String()
+	}
+	return ""
+}
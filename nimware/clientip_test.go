@@ -0,0 +1,81 @@
+package nimware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+func TestIPResolver(t *testing.T) {
+	for _, tt := range []struct {
+		header     string
+		remoteAddr string
+		headers    []string
+		want       string
+	}{
+		// untrusted peers cannot spoof their address
+		{"X-Forwarded-For", "203.0.113.9:1234", []string{"X-Forwarded-For", "1.2.3.4"}, "203.0.113.9"},
+		{"X-Forwarded-For", "10.0.0.2:1234", nil, "10.0.0.2"},
+
+		// trusted proxies are skipped from the right
+		{"X-Forwarded-For", "10.0.0.2:1234", []string{"X-Forwarded-For", "1.2.3.4, 198.51.100.7, 10.1.1.1"}, "198.51.100.7"},
+		{"X-Forwarded-For", "10.0.0.2:1234", []string{"X-Forwarded-For", "10.3.3.3, 192.168.1.1"}, "10.3.3.3"},
+		{"X-Forwarded-For", "10.0.0.2:1234", []string{"X-Forwarded-For", "1.2.3.4, garbage"}, "10.0.0.2"},
+		{"X-Real-IP", "[2001:db8::1]:443", []string{"X-Real-IP", "198.51.100.7"}, "198.51.100.7"},
+
+		// Forwarded supports ports and IPv6
+		{"Forwarded", "192.168.1.1:80", []string{
+			"Forwarded", `for="[2001:db8:cafe::17]:4711";proto=https, for=10.2.2.2`,
+			"X-Forwarded-For", "1.2.3.4",
+		}, "2001:db8:cafe::17"},
+		{"Forwarded", "192.168.1.1:80", []string{"Forwarded", "for=192.0.2.60:47011;by=203.0.113.43"}, "192.0.2.60"},
+		{"Forwarded", "192.168.1.1:80", []string{"Forwarded", "for=unknown"}, "192.168.1.1"},
+
+		// a proxy that only appends to X-Forwarded-For passes the other headers through
+		{"X-Forwarded-For", "10.0.0.5:1234", []string{
+			"X-Forwarded-For", "203.0.113.9",
+			"Forwarded", "for=6.6.6.6",
+			"X-Real-IP", "6.6.6.6",
+		}, "203.0.113.9"},
+		{"X-Forwarded-For", "10.0.0.5:1234", []string{"Forwarded", "for=6.6.6.6", "X-Real-IP", "6.6.6.6"}, "10.0.0.5"},
+	} {
+		res, err := NewIPResolver([]string{"10.0.0.0/8", "192.168.1.1", "2001:db8::1"}, IPResolverHeader(tt.header))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req := newRequest("GET", "/", tt.headers...)
+		req.RemoteAddr = tt.remoteAddr
+		expect(t, res.Resolve(req), tt.want)
+	}
+}
+
+func TestNewIPResolverInvalid(t *testing.T) {
+	if _, err := NewIPResolver([]string{"not-an-ip"}); err == nil {
+		t.Error("Expected an error for an invalid proxy")
+	}
+}
+
+func TestIPResolverSharedWithLogger(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	res, _ := NewIPResolver([]string{"10.0.0.0/8"})
+	resolved := ""
+
+	n := nimble.New()
+	n.WithHandler(res)
+	n.WithHandler(NewStructuredLogger(NewLogfmtSink(buff)))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		resolved = ClientIP(r)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.RemoteAddr = "10.0.0.2:1234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.7")
+	n.ServeHTTP(httptest.NewRecorder(), req)
+
+	expect(t, resolved, "198.51.100.7")
+	expect(t, strings.Contains(buff.String(), "client_ip=198.51.100.7"), true)
+}
@@ -0,0 +1,368 @@
+package nimware
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+// EncoderFunc returns a writer that compresses to w at the given level.
+type EncoderFunc func(w io.Writer, level int) (io.WriteCloser, error)
+
+// CompressOption configures a Compress middleware.
+type CompressOption func(*Compress)
+
+// CompressLevel sets the compression level passed to the encoders.
+func CompressLevel(level int) CompressOption {
+	return func(c *Compress) {
+		c.level = level
+	}
+}
+
+// CompressMinSize sets the size in bytes below which responses are not compressed.
+func CompressMinSize(minSize int) CompressOption {
+	return func(c *Compress) {
+		c.minSize = minSize
+	}
+}
+
+// CompressEncoder registers an encoder for the content coding, such as "br". Encoders
+// registered later are preferred when the client accepts several codings equally.
+func CompressEncoder(coding string, encoder EncoderFunc) CompressOption {
+	return func(c *Compress) {
+		coding = strings.ToLower(coding)
+		if _, ok := c.encoders[coding]; !ok {
+			c.codings = append([]string{coding}, c.codings...)
+		}
+		c.encoders[coding] = encoder
+	}
+}
+
+// CompressSkipTypes adds content types, or prefixes of them such as "image/",
+// that are not compressed.
+func CompressSkipTypes(types ...string) CompressOption {
+	return func(c *Compress) {
+		c.skipTypes = append(c.skipTypes, types...)
+	}
+}
+
+// NewCompress returns a new instance of Compress
+func NewCompress(opts ...CompressOption) *Compress {
+	c := &Compress{
+		level:   gzip.DefaultCompression,
+		minSize: 1024,
+		encoders: map[string]EncoderFunc{
+			"gzip": func(w io.Writer, level int) (io.WriteCloser, error) {
+				return gzip.NewWriterLevel(w, level)
+			},
+			"deflate": func(w io.Writer, level int) (io.WriteCloser, error) {
+				return flate.NewWriter(w, level)
+			},
+		},
+		codings: []string{"gzip", "deflate"},
+		skipTypes: []string{
+			"image/", "video/", "audio/", "font/woff",
+			"application/zip", "application/gzip", "application/x-gzip",
+			"application/x-bzip2", "application/x-7z-compressed", "application/x-rar-compressed",
+		},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Compress is a middleware that compresses responses with the best content coding
+// accepted by the client. Responses that are small, already encoded, partial, or of
+// an already compressed content type are sent as they are. The ETag of a compressed
+// response is made weak, since it no longer validates the exact bytes sent.
+//
+// The nimble.Writer passed to the next handlers reports the uncompressed body size
+// from Size, and implements CompressedSizer to report the bytes sent to the client.
+type Compress struct {
+	level     int
+	minSize   int
+	encoders  map[string]EncoderFunc
+	codings   []string
+	skipTypes []string
+}
+
+func (c *Compress) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	addVary(w.Header(), "Accept-Encoding")
+
+	ww, ok := w.(nimble.Writer)
+	coding := c.negotiate(r.Header.Get("Accept-Encoding"))
+	if !ok || coding == "" || r.Method == http.MethodHead {
+		next(w, r)
+		return
+	}
+
+	cw := &compressWriter{Writer: ww, compress: c, coding: coding}
+	defer cw.close()
+	next(cw, r)
+}
+
+// addVary adds the header name to the Vary header unless it is already listed.
+func addVary(h http.Header, name string) {
+	for _, value := range h.Values("Vary") {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v == "*" || strings.EqualFold(v, name) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", name)
+}
+
+// negotiate returns the registered coding with the highest quality in the
+// Accept-Encoding header, or "" if none is acceptable.
+func (c *Compress) negotiate(accept string) string {
+	if codings := acceptedEncodings(accept, c.codings); len(codings) > 0 {
+		return codings[0]
+	}
+	return ""
+}
+
+// acceptedEncodings returns the codings accepted by the Accept-Encoding header,
+// from the highest quality to the lowest. Codings of equal quality keep their order.
+func acceptedEncodings(accept string, codings []string) []string {
+	if accept == "" {
+		return nil
+	}
+
+	quality := map[string]float64{}
+	wildcard := -1.0
+	for _, part := range strings.Split(accept, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if coding == "*" {
+			wildcard = q
+		} else {
+			quality[coding] = q
+		}
+	}
+
+	qualityOf := func(coding string) float64 {
+		if q, ok := quality[coding]; ok {
+			return q
+		}
+		return wildcard
+	}
+
+	var accepted []string
+	for _, coding := range codings {
+		if qualityOf(coding) > 0 {
+			accepted = append(accepted, coding)
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return qualityOf(accepted[i]) > qualityOf(accepted[j])
+	})
+	return accepted
+}
+
+func (c *Compress) skipType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	for _, t := range c.skipTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompressedSizer is implemented by the nimble.Writer that Compress passes to the
+// next handlers, letting logging and metrics middleware after it report both sizes.
+type CompressedSizer interface {
+	// CompressedSize returns the number of bytes written to the client so far.
+	CompressedSize() int
+}
+
+// Make sure compressWriter conforms with the CompressedSizer interface
+var _ CompressedSizer = (*compressWriter)(nil)
+
+// compressWriter buffers the start of the response until it can decide whether to
+// compress it, then streams it through the encoder.
+type compressWriter struct {
+	nimble.Writer
+	compress *Compress
+	coding   string
+	encoder  io.WriteCloser
+	status   int
+	size     int
+	buf      []byte
+	decided  bool
+}
+
+func (cw *compressWriter) WriteHeader(s int) {
+	if cw.status != 0 || cw.Hijacked() {
+		return
+	}
+	if s >= 100 && s < 200 && s != http.StatusSwitchingProtocols {
+		// informational responses such as 103 Early Hints precede the final status
+		cw.Writer.WriteHeader(s)
+		return
+	}
+	cw.status = s
+
+	h := cw.Header()
+	switch {
+	case s < http.StatusOK || s == http.StatusNoContent || s == http.StatusNotModified:
+		cw.decide(false)
+	case h.Get("Content-Encoding") != "" || h.Get("Content-Range") != "":
+		cw.decide(false)
+	case h.Get("Content-Type") != "" && cw.compress.skipType(h.Get("Content-Type")):
+		cw.decide(false)
+	default:
+		if n, err := strconv.Atoi(h.Get("Content-Length")); err == nil && n < cw.compress.minSize {
+			cw.decide(false)
+		}
+	}
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if cw.Hijacked() {
+		return 0, http.ErrHijacked
+	}
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	cw.size += len(b)
+
+	if !cw.decided {
+		cw.buf = append(cw.buf, b...)
+		if len(cw.buf) < cw.compress.minSize {
+			return len(b), nil
+		}
+		if err := cw.commit(true); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.Writer.Write(b)
+}
+
+// decide writes the header, compressing the body if compress is set and the
+// response is eligible.
+func (cw *compressWriter) decide(compress bool) {
+	cw.decided = true
+
+	h := cw.Header()
+	if compress && h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(cw.buf))
+	}
+	if compress && !cw.compress.skipType(h.Get("Content-Type")) {
+		if encoder, err := cw.compress.encoders[cw.coding](cw.Writer, cw.compress.level); err == nil {
+			cw.encoder = encoder
+			h.Set("Content-Encoding", cw.coding)
+			h.Del("Content-Length")
+			// the encoded body is not byte-for-byte the one a strong ETag validates
+			if etag := h.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+				h.Set("ETag", "W/"+etag)
+			}
+		}
+	}
+	cw.Writer.WriteHeader(cw.status)
+}
+
+// commit decides and writes out any buffered body.
+func (cw *compressWriter) commit(compress bool) error {
+	if !cw.decided {
+		cw.decide(compress)
+	}
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	var err error
+	if cw.encoder != nil {
+		_, err = cw.encoder.Write(buf)
+	} else {
+		_, err = cw.Writer.Write(buf)
+	}
+	return err
+}
+
+func (cw *compressWriter) close() {
+	if cw.status == 0 || cw.Hijacked() {
+		return
+	}
+	cw.commit(false)
+	if cw.encoder != nil {
+		cw.encoder.Close()
+	}
+}
+
+func (cw *compressWriter) Flush() {
+	if cw.Hijacked() {
+		return
+	}
+	if cw.status == 0 {
+		cw.WriteHeader(http.StatusOK)
+	}
+	cw.commit(true)
+	if f, ok := cw.encoder.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+	cw.Writer.Flush()
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := cw.Writer.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("the ResponseWriter doesn't support the http.Hijack interface")
+	}
+	return hijacker.Hijack()
+}
+
+// Unwrap returns the wrapped Writer for use by http.ResponseController.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.Writer
+}
+
+// Status returns the status code written by the handler.
+func (cw *compressWriter) Status() int {
+	if cw.status == 0 && cw.Hijacked() {
+		return cw.Writer.Status()
+	}
+	return cw.status
+}
+
+// Written returns whether or not the handler has written the status.
+func (cw *compressWriter) Written() bool {
+	return cw.Status() != 0
+}
+
+// Size returns the size of the uncompressed response body.
+func (cw *compressWriter) Size() int {
+	return cw.size
+}
+
+// CompressedSize returns the number of bytes written to the client so far.
+func (cw *compressWriter) CompressedSize() int {
+	return cw.Writer.Size()
+}
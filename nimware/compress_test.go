@@ -0,0 +1,237 @@
+package nimware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+func TestCompressGzip(t *testing.T) {
+	body := strings.Repeat("hello nimble ", 200)
+	uncompressed, compressed := 0, 0
+	var inner nimble.Writer
+
+	req := newRequest("GET", "/", "Accept-Encoding", "deflate;q=0.5, gzip")
+	rec := serve(req, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2600")
+		w.Write([]byte(body))
+		w.(http.Flusher).Flush()
+		uncompressed = w.(nimble.Writer).Size()
+		compressed = w.(CompressedSizer).CompressedSize()
+		inner = w.(*compressWriter).Writer
+	}, NewCompress())
+
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Header().Get("Content-Length"), "")
+	expect(t, rec.Header().Get("Vary"), "Accept-Encoding")
+	expect(t, rec.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	expect(t, uncompressed, len(body))
+	expect(t, inner.Size(), rec.Body.Len())
+	expect(t, compressed > 0 && compressed < uncompressed, true)
+
+	zr, err := gzip.NewReader(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(zr)
+	expect(t, string(b), body)
+}
+
+func TestCompressDeflate(t *testing.T) {
+	body := strings.Repeat("a", 2048)
+	rec := serve(newRequest("GET", "/", "Accept-Encoding", "gzip;q=0, deflate"), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(body))
+	}, NewCompress())
+
+	expect(t, rec.Header().Get("Content-Encoding"), "deflate")
+	b, _ := ioutil.ReadAll(flate.NewReader(rec.Body))
+	expect(t, string(b), body)
+}
+
+func TestCompressSkips(t *testing.T) {
+	large := strings.Repeat("a", 2048)
+
+	cases := []struct {
+		name    string
+		accept  string
+		handler http.HandlerFunc
+	}{
+		{"not accepted", "identity", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(large))
+		}},
+		{"small body", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("small"))
+		}},
+		{"compressed type", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write([]byte(large))
+		}},
+		{"already encoded", "gzip", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Encoding", "br")
+			w.Write([]byte(large))
+		}},
+	}
+
+	for _, tc := range cases {
+		rec := serve(newRequest("GET", "/", "Accept-Encoding", tc.accept), tc.handler, NewCompress())
+		if rec.Header().Get("Content-Encoding") == "gzip" {
+			t.Errorf("%s: expected the response not to be compressed", tc.name)
+		}
+		expect(t, rec.Header().Get("Vary"), "Accept-Encoding")
+	}
+
+	rec := serve(newRequest("GET", "/", "Accept-Encoding", "gzip"), func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}, NewCompress())
+	expect(t, rec.Code, http.StatusNoContent)
+	expect(t, rec.Body.Len(), 0)
+}
+
+func TestCompressFlush(t *testing.T) {
+	rec := serve(newRequest("GET", "/", "Accept-Encoding", "gzip"), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("data: first\n\n"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("data: second\n\n"))
+	}, NewCompress())
+
+	expect(t, rec.Flushed, true)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	zr, _ := gzip.NewReader(rec.Body)
+	b, _ := ioutil.ReadAll(zr)
+	expect(t, string(b), "data: first\n\ndata: second\n\n")
+}
+
+func TestCompressEarlyHints(t *testing.T) {
+	body := strings.Repeat("hello nimble ", 400)
+	status := 0
+	n := nimble.New()
+	n.WithHandler(NewCompress())
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+		w.WriteHeader(http.StatusEarlyHints)
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(body))
+		status = w.(nimble.Writer).Status()
+	})
+	server := httptest.NewServer(n)
+	defer server.Close()
+
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+
+	expect(t, status, http.StatusCreated)
+	expect(t, res.StatusCode, http.StatusCreated)
+	expect(t, res.Header.Get("Content-Encoding"), "gzip")
+	zr, err := gzip.NewReader(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(zr)
+	expect(t, string(b), body)
+}
+
+type upperWriteCloser struct {
+	w io.Writer
+}
+
+func (u upperWriteCloser) Write(b []byte) (int, error) { return u.w.Write(bytes.ToUpper(b)) }
+func (u upperWriteCloser) Close() error                { return nil }
+
+func TestCompressCustomEncoder(t *testing.T) {
+	upper := func(w io.Writer, level int) (io.WriteCloser, error) {
+		return upperWriteCloser{w}, nil
+	}
+	c := NewCompress(CompressEncoder("upper", upper), CompressMinSize(0))
+
+	rec := serve(newRequest("GET", "/", "Accept-Encoding", "gzip, upper"), func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("shout"))
+	}, c)
+	expect(t, rec.Header().Get("Content-Encoding"), "upper")
+	expect(t, rec.Body.String(), "SHOUT")
+}
+
+func TestCompressHijack(t *testing.T) {
+	serve(newRequest("GET", "/", "Accept-Encoding", "gzip"), func(w http.ResponseWriter, r *http.Request) {
+		_, ok := w.(http.Hijacker)
+		expect(t, ok, true)
+	}, NewCompress())
+}
+
+func TestCompressHijacked(t *testing.T) {
+	var written bool
+	var status int
+	var err error
+	n := nimble.New()
+	n.WithHandler(NewCompress())
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Hijacker).Hijack()
+		ww := w.(nimble.Writer)
+		written, status = ww.Written(), ww.Status()
+		_, err = w.Write([]byte("late"))
+	})
+
+	rec := hijackRecorder{httptest.NewRecorder()}
+	req := httptest.NewRequest("GET", "/ws", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	n.ServeHTTP(rec, req)
+
+	expect(t, written, true)
+	expect(t, status, http.StatusSwitchingProtocols)
+	expect(t, err, http.ErrHijacked)
+	expect(t, rec.Body.Len(), 0)
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+}
+
+func TestCompressStaticETag(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.js"), []byte(strings.Repeat("console.log('hello');\n", 100)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	n := nimble.New()
+	n.WithHandler(NewCompress())
+	n.WithHandler(NewStatic(http.Dir(dir)))
+
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/app.js", nil)
+	n.ServeHTTP(rec, req)
+	strong := rec.Header().Get("ETag")
+	expect(t, strings.HasPrefix(strong, `"`), true)
+
+	rec = httptest.NewRecorder()
+	req.Header.Set("Accept-Encoding", "gzip")
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Header().Get("ETag"), "W/"+strong)
+
+	// the weak validator still revalidates the compressed response
+	rec = httptest.NewRecorder()
+	req.Header.Set("If-None-Match", "W/"+strong)
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Code, http.StatusNotModified)
+
+	// but cannot resume it with a range
+	rec = httptest.NewRecorder()
+	req.Header.Del("If-None-Match")
+	req.Header.Set("Range", "bytes=0-9")
+	req.Header.Set("If-Range", "W/"+strong)
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+}
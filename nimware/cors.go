@@ -0,0 +1,233 @@
+package nimware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSOption configures a CORS middleware.
+type CORSOption func(*CORS)
+
+// CORSOrigins sets the allowed origins. An origin is either exact, such as
+// "https://example.com", a wildcard subdomain such as "https://*.example.com",
+// or "*" to allow any origin.
+func CORSOrigins(origins ...string) CORSOption {
+	return func(c *CORS) {
+		c.origins = make([]string, 0, len(origins))
+		c.allowAll = false
+		for _, origin := range origins {
+			if origin == "*" {
+				c.allowAll = true
+			}
+			c.origins = append(c.origins, strings.ToLower(origin))
+		}
+	}
+}
+
+// CORSOriginFunc sets a predicate that allows origins in addition to CORSOrigins.
+// Without CORSOrigins, only the origins allowed by the predicate are allowed.
+func CORSOriginFunc(allow func(origin string) bool) CORSOption {
+	return func(c *CORS) {
+		c.originFunc = allow
+	}
+}
+
+// CORSMethods sets the methods allowed for cross-origin requests.
+func CORSMethods(methods ...string) CORSOption {
+	return func(c *CORS) {
+		c.methods = nil
+		for _, m := range methods {
+			c.methods = append(c.methods, strings.ToUpper(m))
+		}
+	}
+}
+
+// CORSHeaders sets the request headers allowed for cross-origin requests.
+// "*" allows any header.
+func CORSHeaders(headers ...string) CORSOption {
+	return func(c *CORS) {
+		c.headers = nil
+		for _, h := range headers {
+			c.headers = append(c.headers, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// CORSExposedHeaders sets the response headers that browsers expose to scripts.
+func CORSExposedHeaders(headers ...string) CORSOption {
+	return func(c *CORS) {
+		c.exposed = nil
+		for _, h := range headers {
+			c.exposed = append(c.exposed, http.CanonicalHeaderKey(h))
+		}
+	}
+}
+
+// CORSCredentials sets whether cross-origin requests may include credentials.
+// Credentials require the allowed origins to be listed, so combining them with
+// the "*" origin makes NewCORS panic.
+func CORSCredentials(allow bool) CORSOption {
+	return func(c *CORS) {
+		c.credentials = allow
+	}
+}
+
+// CORSMaxAge sets how long browsers may cache the result of a preflight request.
+func CORSMaxAge(maxAge time.Duration) CORSOption {
+	return func(c *CORS) {
+		c.maxAge = maxAge
+	}
+}
+
+// NewCORS returns a new instance of CORS. By default it allows any origin
+// to make GET, HEAD and POST requests without credentials. It panics if
+// credentials are allowed for any origin.
+func NewCORS(opts ...CORSOption) *CORS {
+	c := &CORS{
+		methods: []string{http.MethodGet, http.MethodHead, http.MethodPost},
+		headers: []string{"Accept", "Accept-Language", "Content-Language", "Content-Type", "X-Requested-With"},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.origins == nil && c.originFunc == nil {
+		c.origins, c.allowAll = []string{"*"}, true
+	}
+	if c.allowAll && c.credentials {
+		panic(`nimware: CORS credentials cannot be allowed for the "*" origin, list the origins instead`)
+	}
+	return c
+}
+
+// CORS is a middleware that implements Cross-Origin Resource Sharing. Preflight
+// requests are answered directly without calling the next middleware.
+type CORS struct {
+	origins     []string
+	allowAll    bool
+	originFunc  func(origin string) bool
+	methods     []string
+	headers     []string
+	exposed     []string
+	credentials bool
+	maxAge      time.Duration
+}
+
+func (c *CORS) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	h := w.Header()
+	origin := r.Header.Get("Origin")
+	preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+	if !c.allowAll {
+		addVary(h, "Origin")
+	}
+	if preflight {
+		addVary(h, "Access-Control-Request-Method")
+		addVary(h, "Access-Control-Request-Headers")
+	}
+
+	if origin == "" || !c.allowOrigin(origin) {
+		if preflight {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+		return
+	}
+
+	if preflight {
+		c.preflight(w, r, origin)
+		return
+	}
+
+	c.setOrigin(h, origin)
+	if len(c.exposed) > 0 {
+		h.Set("Access-Control-Expose-Headers", strings.Join(c.exposed, ", "))
+	}
+	next(w, r)
+}
+
+func (c *CORS) preflight(w http.ResponseWriter, r *http.Request, origin string) {
+	method := strings.ToUpper(r.Header.Get("Access-Control-Request-Method"))
+	headers := requestedHeaders(r.Header.Get("Access-Control-Request-Headers"))
+	if !c.allowMethod(method) || !c.allowHeaders(headers) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h := w.Header()
+	c.setOrigin(h, origin)
+	h.Set("Access-Control-Allow-Methods", strings.Join(c.methods, ", "))
+	if len(headers) > 0 {
+		h.Set("Access-Control-Allow-Headers", strings.Join(headers, ", "))
+	}
+	if c.maxAge > 0 {
+		h.Set("Access-Control-Max-Age", strconv.Itoa(int(c.maxAge/time.Second)))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *CORS) setOrigin(h http.Header, origin string) {
+	if c.allowAll {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+func (c *CORS) allowOrigin(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+	lower := strings.ToLower(origin)
+	for _, allowed := range c.origins {
+		if i := strings.Index(allowed, "://*."); i >= 0 {
+			scheme, domain := allowed[:i+3], allowed[i+4:]
+			if strings.HasPrefix(lower, scheme) && strings.HasSuffix(lower, domain) && len(lower) > len(scheme)+len(domain) {
+				return true
+			}
+		} else if allowed == lower {
+			return true
+		}
+	}
+	return c.originFunc != nil && c.originFunc(origin)
+}
+
+func (c *CORS) allowMethod(method string) bool {
+	for _, m := range c.methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) allowHeaders(headers []string) bool {
+	for _, requested := range headers {
+		allowed := false
+		for _, h := range c.headers {
+			if h == "*" || h == requested {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+func requestedHeaders(value string) []string {
+	var headers []string
+	for _, h := range strings.Split(value, ",") {
+		if h = strings.TrimSpace(h); h != "" {
+			headers = append(headers, http.CanonicalHeaderKey(h))
+		}
+	}
+	return headers
+}
@@ -0,0 +1,125 @@
+package nimware
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCORSDefault(t *testing.T) {
+	rec := serve(newRequest("GET", "/api", "Origin", "https://anywhere.com"), hello(http.StatusOK), NewCORS())
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Access-Control-Allow-Origin"), "*")
+	expect(t, rec.Header().Get("Vary"), "")
+
+	rec = serve(newRequest("GET", "/api"), hello(http.StatusOK), NewCORS())
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Access-Control-Allow-Origin"), "")
+}
+
+func TestCORSOrigins(t *testing.T) {
+	c := NewCORS(
+		CORSOrigins("https://example.com", "https://*.example.org"),
+		CORSOriginFunc(func(origin string) bool { return strings.HasSuffix(origin, ".test") }),
+		CORSCredentials(true),
+		CORSExposedHeaders("x-total-count"),
+	)
+
+	for _, origin := range []string{"https://example.com", "https://api.example.org", "http://local.test"} {
+		rec := serve(newRequest("GET", "/api", "Origin", origin), hello(http.StatusOK), c)
+		expect(t, rec.Code, http.StatusOK)
+		expect(t, rec.Header().Get("Access-Control-Allow-Origin"), origin)
+		expect(t, rec.Header().Get("Access-Control-Allow-Credentials"), "true")
+		expect(t, rec.Header().Get("Access-Control-Expose-Headers"), "X-Total-Count")
+		expect(t, rec.Header().Get("Vary"), "Origin")
+	}
+
+	for _, origin := range []string{"https://evil.com", "https://example.org", "http://api.example.org"} {
+		rec := serve(newRequest("GET", "/api", "Origin", origin), hello(http.StatusOK), c)
+		expect(t, rec.Code, http.StatusOK)
+		expect(t, rec.Header().Get("Access-Control-Allow-Origin"), "")
+		expect(t, rec.Header().Get("Vary"), "Origin")
+	}
+}
+
+func TestCORSOriginFunc(t *testing.T) {
+	c := NewCORS(
+		CORSOriginFunc(func(origin string) bool { return origin == "https://example.com" }),
+		CORSCredentials(true),
+	)
+
+	rec := serve(newRequest("GET", "/api", "Origin", "https://example.com"), hello(http.StatusOK), c)
+	expect(t, rec.Header().Get("Access-Control-Allow-Origin"), "https://example.com")
+	expect(t, rec.Header().Get("Access-Control-Allow-Credentials"), "true")
+
+	rec = serve(newRequest("GET", "/api", "Origin", "https://evil.com"), hello(http.StatusOK), c)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Access-Control-Allow-Origin"), "")
+	expect(t, rec.Header().Get("Access-Control-Allow-Credentials"), "")
+}
+
+func TestCORSPreflight(t *testing.T) {
+	c := NewCORS(
+		CORSOrigins("https://example.com"),
+		CORSMethods("GET", "PUT"),
+		CORSHeaders("Content-Type", "Authorization"),
+		CORSMaxAge(10*time.Minute),
+	)
+	reached := false
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+		w.WriteHeader(http.StatusOK)
+	}
+
+	// preflights are answered with 204 without reaching the handler
+	rec := serve(newRequest("OPTIONS", "/api",
+		"Origin", "https://example.com",
+		"Access-Control-Request-Method", "PUT",
+		"Access-Control-Request-Headers", "authorization, content-type",
+	), handler, c)
+	expect(t, rec.Code, http.StatusNoContent)
+	expect(t, reached, false)
+	expect(t, rec.Header().Get("Access-Control-Allow-Origin"), "https://example.com")
+	expect(t, rec.Header().Get("Access-Control-Allow-Methods"), "GET, PUT")
+	expect(t, rec.Header().Get("Access-Control-Allow-Headers"), "Authorization, Content-Type")
+	expect(t, rec.Header().Get("Access-Control-Max-Age"), "600")
+	expect(t, strings.Join(rec.Header().Values("Vary"), ", "), "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
+
+	rec = serve(newRequest("OPTIONS", "/api",
+		"Origin", "https://example.com",
+		"Access-Control-Request-Method", "DELETE",
+	), handler, c)
+	expect(t, rec.Code, http.StatusNoContent)
+	expect(t, reached, false)
+	expect(t, rec.Header().Get("Access-Control-Allow-Origin"), "")
+
+	rec = serve(newRequest("OPTIONS", "/api",
+		"Origin", "https://example.com",
+		"Access-Control-Request-Method", "GET",
+		"Access-Control-Request-Headers", "X-Custom",
+	), handler, c)
+	expect(t, rec.Code, http.StatusNoContent)
+	expect(t, reached, false)
+	expect(t, rec.Header().Get("Access-Control-Allow-Origin"), "")
+
+	// plain OPTIONS requests are not preflights
+	rec = serve(newRequest("OPTIONS", "/api", "Origin", "https://example.com"), handler, c)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, reached, true)
+}
+
+func TestCORSCredentialsWithAnyOrigin(t *testing.T) {
+	for _, opts := range [][]CORSOption{
+		{CORSCredentials(true)},
+		{CORSOrigins("https://example.com", "*"), CORSCredentials(true)},
+	} {
+		func() {
+			defer func() {
+				refute(t, recover(), nil)
+			}()
+			NewCORS(opts...)
+			t.Error("Expected NewCORS to panic")
+		}()
+	}
+}
@@ -0,0 +1,185 @@
+package nimware
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Apache access log format presets, for use with NewApacheFormat.
+const (
+	// CommonLogFormat is the NCSA Common Log Format.
+	CommonLogFormat = `%h %l %u %t "%r" %>s %b`
+	// CombinedLogFormat is the NCSA Combined Log Format, which adds the referer and user agent.
+	CombinedLogFormat = `%h %l %u %t "%r" %>s %b "%{Referer}i" "%{User-Agent}i"`
+)
+
+// Formatter formats a LogRecord as an access log line.
+type Formatter interface {
+	Format(rec *LogRecord) string
+}
+
+// templateFormat formats records with a text/template.
+type templateFormat struct {
+	tmpl *template.Template
+}
+
+// NewTemplateFormat returns a Formatter that executes the text/template against
+// each LogRecord, for example:
+//
+//	{{.Start.Format "2006/01/02 - 15:04:05"}} {{.Method}} {{.Path}} {{.Status}} {{.Latency}}
+func NewTemplateFormat(text string) (Formatter, error) {
+	tmpl, err := template.New("logger").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &templateFormat{tmpl}, nil
+}
+
+func (f *templateFormat) Format(rec *LogRecord) string {
+	var buf bytes.Buffer
+	if err := f.tmpl.Execute(&buf, rec); err != nil {
+		return err.Error()
+	}
+	return buf.String()
+}
+
+// apacheFormat formats records with the directives of an Apache LogFormat string.
+type apacheFormat struct {
+	parts []func(buf *bytes.Buffer, rec *LogRecord)
+}
+
+// NewApacheFormat returns a Formatter for an Apache mod_log_config format string,
+// such as CommonLogFormat or CombinedLogFormat. The supported directives are
+// %a %h %l %u %t %r %s %>s %b %B %D %T %m %U %q %H %% and %{Header}i.
+func NewApacheFormat(format string) (Formatter, error) {
+	f := &apacheFormat{}
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' {
+			j := strings.IndexByte(format[i:], '%')
+			if j < 0 {
+				j = len(format) - i
+			}
+			f.literal(format[i : i+j])
+			i += j - 1
+			continue
+		}
+
+		i++
+		if i < len(format) && format[i] == '>' {
+			i++
+		}
+		if i >= len(format) {
+			return nil, fmt.Errorf("nimware: incomplete directive at end of %q", format)
+		}
+
+		var arg string
+		if format[i] == '{' {
+			j := strings.IndexByte(format[i:], '}')
+			if j < 0 || i+j+1 >= len(format) {
+				return nil, fmt.Errorf("nimware: unterminated directive in %q", format)
+			}
+			arg = format[i+1 : i+j]
+			i += j + 1
+		}
+
+		part, ok := apacheDirective(format[i], arg)
+		if !ok {
+			return nil, fmt.Errorf("nimware: unsupported directive %%%c in %q", format[i], format)
+		}
+		f.parts = append(f.parts, part)
+	}
+	return f, nil
+}
+
+func (f *apacheFormat) literal(s string) {
+	f.parts = append(f.parts, func(buf *bytes.Buffer, rec *LogRecord) {
+		buf.WriteString(s)
+	})
+}
+
+func (f *apacheFormat) Format(rec *LogRecord) string {
+	var buf bytes.Buffer
+	for _, part := range f.parts {
+		part(&buf, rec)
+	}
+	return buf.String()
+}
+
+// apacheEscaper escapes values so they cannot break out of quoted fields.
+var apacheEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+func apacheDirective(c byte, arg string) (func(buf *bytes.Buffer, rec *LogRecord), bool) {
+	str := func(fn func(rec *LogRecord) string) func(buf *bytes.Buffer, rec *LogRecord) {
+		return func(buf *bytes.Buffer, rec *LogRecord) {
+			if s := fn(rec); s != "" {
+				buf.WriteString(apacheEscaper.Replace(s))
+			} else {
+				buf.WriteByte('-')
+			}
+		}
+	}
+
+	switch c {
+	case '%':
+		return func(buf *bytes.Buffer, rec *LogRecord) { buf.WriteByte('%') }, true
+	case 'a', 'h':
+		return str(func(rec *LogRecord) string { return rec.ClientIP }), true
+	case 'l':
+		return str(func(rec *LogRecord) string { return "" }), true
+	case 'u':
+		return str(func(rec *LogRecord) string { return rec.RemoteUser }), true
+	case 't':
+		return func(buf *bytes.Buffer, rec *LogRecord) {
+			buf.WriteString(rec.Start.Format("[02/Jan/2006:15:04:05 -0700]"))
+		}, true
+	case 'r':
+		return func(buf *bytes.Buffer, rec *LogRecord) {
+			buf.WriteString(apacheEscaper.Replace(rec.Method + " " + rec.URI() + " " + rec.Proto))
+		}, true
+	case 's':
+		return func(buf *bytes.Buffer, rec *LogRecord) { buf.WriteString(strconv.Itoa(rec.Status)) }, true
+	case 'b':
+		return func(buf *bytes.Buffer, rec *LogRecord) {
+			if rec.Size == 0 {
+				buf.WriteByte('-')
+				return
+			}
+			buf.WriteString(strconv.Itoa(rec.Size))
+		}, true
+	case 'B':
+		return func(buf *bytes.Buffer, rec *LogRecord) { buf.WriteString(strconv.Itoa(rec.Size)) }, true
+	case 'D':
+		return func(buf *bytes.Buffer, rec *LogRecord) {
+			buf.WriteString(strconv.FormatInt(int64(rec.Latency/time.Microsecond), 10))
+		}, true
+	case 'T':
+		return func(buf *bytes.Buffer, rec *LogRecord) {
+			buf.WriteString(strconv.FormatInt(int64(rec.Latency/time.Second), 10))
+		}, true
+	case 'm':
+		return str(func(rec *LogRecord) string { return rec.Method }), true
+	case 'U':
+		return str(func(rec *LogRecord) string { return rec.Path }), true
+	case 'q':
+		return func(buf *bytes.Buffer, rec *LogRecord) {
+			if rec.Query != "" {
+				buf.WriteString("?" + rec.Query)
+			}
+		}, true
+	case 'H':
+		return str(func(rec *LogRecord) string { return rec.Proto }), true
+	case 'i':
+		if arg == "" {
+			return nil, false
+		}
+		name := http.CanonicalHeaderKey(arg)
+		return str(func(rec *LogRecord) string { return rec.Header.Get(name) }), true
+	}
+	return nil, false
+}
@@ -0,0 +1,95 @@
+package nimware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+func testRecord() *LogRecord {
+	header := http.Header{}
+	header.Set("Referer", "http://example.com/start")
+	header.Set("User-Agent", `Mozilla/5.0 "quoted"`)
+
+	return &LogRecord{
+		Start:      time.Date(2000, time.October, 10, 13, 55, 36, 0, time.FixedZone("", -7*3600)),
+		Method:     "GET",
+		Path:       "/apache_pb.gif",
+		Query:      "size=2",
+		Status:     200,
+		Size:       2326,
+		Latency:    1500 * time.Millisecond,
+		ClientIP:   "127.0.0.1",
+		Proto:      "HTTP/1.0",
+		RemoteUser: "frank",
+		Header:     header,
+	}
+}
+
+func TestApacheFormatPresets(t *testing.T) {
+	common, err := NewApacheFormat(CommonLogFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, common.Format(testRecord()),
+		`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?size=2 HTTP/1.0" 200 2326`)
+
+	combined, err := NewApacheFormat(CombinedLogFormat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, combined.Format(testRecord()),
+		`127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif?size=2 HTTP/1.0" 200 2326 "http://example.com/start" "Mozilla/5.0 \"quoted\""`)
+}
+
+func TestApacheFormatDirectives(t *testing.T) {
+	f, err := NewApacheFormat(`%m %U%q %H %s %B %D %T %{X-Missing}i 100%%`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := testRecord()
+	rec.Size = 0
+	expect(t, f.Format(rec), "GET /apache_pb.gif?size=2 HTTP/1.0 200 0 1500000 1 - 100%")
+
+	for _, bad := range []string{"%", "%{Referer", "%z", "%{}i"} {
+		if _, err := NewApacheFormat(bad); err == nil {
+			t.Errorf("Expected format %q to be rejected", bad)
+		}
+	}
+}
+
+func TestTemplateFormat(t *testing.T) {
+	f, err := NewTemplateFormat(`{{.Start.Format "2006-01-02"}} {{.Method}} {{.Path}} {{.Status}} {{.Latency}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect(t, f.Format(testRecord()), "2000-10-10 GET /apache_pb.gif 200 1.5s")
+
+	if _, err := NewTemplateFormat("{{.Method"); err == nil {
+		t.Error("Expected an invalid template to be rejected")
+	}
+}
+
+func TestFormatLogger(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	f, _ := NewApacheFormat(`%h "%r" %>s %b`)
+	l := NewFormatLogger(f)
+	l.Logger = log.New(buff, "", 0)
+
+	n := nimble.New()
+	n.WithHandler(l)
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("hello"))
+	})
+
+	req := httptest.NewRequest("GET", "/foobar?q=1", nil)
+	req.RemoteAddr = "10.0.0.1:5000"
+	n.ServeHTTP(httptest.NewRecorder(), req)
+
+	expect(t, buff.String(), "10.0.0.1 \"GET /foobar?q=1 HTTP/1.1\" 200 5\n")
+}
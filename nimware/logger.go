@@ -18,32 +18,110 @@ type ALogger interface {
 // Logger is a middleware that logs per request.
 type Logger struct {
 	*log.Logger
-	color bool
+	color  bool
+	sink   Sink
+	level  LevelFunc
+	format Formatter
+}
+
+// LoggerOption configures a Logger.
+type LoggerOption func(*Logger)
+
+// LoggerLevels sets the function that picks the level each request is logged at.
+// It is only used by structured Loggers.
+func LoggerLevels(level LevelFunc) LoggerOption {
+	return func(l *Logger) {
+		l.level = level
+	}
+}
+
+// LoggerFormat sets the Formatter used to write each request as a log line.
+func LoggerFormat(format Formatter) LoggerOption {
+	return func(l *Logger) {
+		l.format = format
+	}
 }
 
 // NewLogger returns a new Logger instance
 func NewLogger() *Logger {
-	return &Logger{log.New(os.Stdout, "[n.] ", 0), false}
+	return &Logger{Logger: log.New(os.Stdout, "[n.] ", 0)}
 }
 
 // NewColorLogger returns a new colored Logger instance
 func NewColorLogger() *Logger {
-	return &Logger{log.New(os.Stdout, "[n.] ", 0), true}
+	return &Logger{Logger: log.New(os.Stdout, "[n.] ", 0), color: true}
+}
+
+// NewStructuredLogger returns a new Logger instance that sends one LogRecord
+// per request to the sink, at the level given by DefaultLevel unless configured otherwise.
+func NewStructuredLogger(sink Sink, opts ...LoggerOption) *Logger {
+	l := &Logger{sink: sink, level: DefaultLevel}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// NewFormatLogger returns a new Logger instance that writes one line per request to
+// stdout in the given format, without a prefix so the output can be read by log analyzers.
+//
+//	format, _ := nimware.NewApacheFormat(nimware.CombinedLogFormat)
+//	l := nimware.NewFormatLogger(format)
+func NewFormatLogger(format Formatter, opts ...LoggerOption) *Logger {
+	l := &Logger{Logger: log.New(os.Stdout, "", 0), format: format}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
 }
 
 func (l *Logger) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	start := time.Now()
 
+	r = withRequestIDHolder(r)
 	next(w, r)
 
 	ww := w.(nimble.Writer)
 
-	clientIP := "0.0.0.0"
-	latency := time.Since(start)
-	method := r.Method
-	path := r.URL.Path
-	statusCode := ww.Status()
-	status := http.StatusText(ww.Status())
+	rec := &LogRecord{
+		Start:     start,
+		Method:    r.Method,
+		Path:      r.URL.Path,
+		Query:     r.URL.RawQuery,
+		Status:    ww.Status(),
+		Size:      ww.Size(),
+		Latency:   time.Since(start),
+		ClientIP:  ClientIP(r),
+		UserAgent: r.UserAgent(),
+		RequestID: GetRequestID(r),
+		Proto:     r.Proto,
+		Header:    r.Header,
+		Hijacked:  ww.Hijacked(),
+	}
+	rec.RemoteUser, _, _ = r.BasicAuth()
+	if t := ww.HeaderTime(); !t.IsZero() {
+		rec.HeaderLatency = t.Sub(start)
+	}
+	if t := ww.FirstByte(); !t.IsZero() {
+		rec.TTFB = t.Sub(start)
+		rec.Streaming = ww.LastWrite().Sub(t)
+	}
+
+	if l.sink != nil {
+		l.sink.Log(l.level(rec.Status), rec)
+		return
+	}
+	if l.format != nil {
+		l.Print(l.format.Format(rec))
+		return
+	}
+
+	clientIP := rec.ClientIP
+	latency := rec.Latency
+	method := rec.Method
+	path := rec.Path
+	statusCode := rec.Status
+	status := http.StatusText(statusCode)
 
 	if l.color {
 		statusColor := colorForStatus(statusCode)
@@ -2,10 +2,14 @@ package nimware
 
 import (
 	"bytes"
+	"encoding/json"
 	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nimgo/nim/nimble"
 )
@@ -33,3 +37,100 @@ func TestLogger(t *testing.T) {
 	expect(t, recorder.Code, http.StatusNotFound)
 	refute(t, len(buff.String()), 0)
 }
+
+func TestStructuredLoggerJSON(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	req := newRequest("GET", "/foobar?q=1", "User-Agent", "nim test", "X-Request-Id", "abc-123")
+	req.RemoteAddr = "10.0.0.1:5000"
+	serve(req, hello(http.StatusNotFound), NewStructuredLogger(NewJSONSink(buff)), NewRequestID())
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buff.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, entry["level"], "WARN")
+	expect(t, entry["method"], "GET")
+	expect(t, entry["path"], "/foobar")
+	expect(t, entry["query"], "q=1")
+	expect(t, entry["status"], float64(404))
+	expect(t, entry["size"], float64(5))
+	expect(t, entry["client_ip"], "10.0.0.1")
+	expect(t, entry["user_agent"], "nim test")
+	expect(t, entry["request_id"], "abc-123")
+	expect(t, entry["proto"], "HTTP/1.1")
+	refute(t, entry["latency_ms"], nil)
+}
+
+func TestStructuredLoggerLogfmt(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	req := newRequest("GET", "/foobar", "User-Agent", "nim test", "X-Request-Id", "abc-123")
+	serve(req, hello(http.StatusOK), NewStructuredLogger(NewLogfmtSink(buff)), NewRequestID())
+
+	line := buff.String()
+	expect(t, strings.Contains(line, " level=INFO method=GET path=/foobar query=\"\" status=200 size=5 "), true)
+	expect(t, strings.Contains(line, " user_agent=\"nim test\" request_id=abc-123 proto=HTTP/1.1 hijacked=false\n"), true)
+}
+
+func TestStructuredLoggerSlog(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	logger := slog.New(slog.NewTextHandler(buff, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	l := NewStructuredLogger(NewSlogSink(logger), LoggerLevels(func(status int) slog.Level {
+		if status == http.StatusTeapot {
+			return slog.LevelError
+		}
+		return slog.LevelDebug
+	}))
+
+	serve(newRequest("GET", "/quiet"), hello(http.StatusOK), l)
+	expect(t, buff.Len(), 0)
+
+	serve(newRequest("GET", "/teapot"), hello(http.StatusTeapot), l)
+	expect(t, strings.Contains(buff.String(), "level=ERROR msg=request method=GET path=/teapot"), true)
+}
+
+func TestStructuredLoggerTiming(t *testing.T) {
+	var rec *LogRecord
+	n := nimble.New()
+	n.WithHandler(NewStructuredLogger(sinkFunc(func(level slog.Level, r *LogRecord) {
+		rec = r
+	})))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("first"))
+		time.Sleep(5 * time.Millisecond)
+		w.Write([]byte("last"))
+	})
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	expect(t, rec.HeaderLatency <= rec.TTFB, true)
+	expect(t, rec.Streaming >= 5*time.Millisecond, true)
+	expect(t, rec.TTFB+rec.Streaming <= rec.Latency, true)
+}
+
+type sinkFunc func(level slog.Level, rec *LogRecord)
+
+func (f sinkFunc) Log(level slog.Level, rec *LogRecord) {
+	f(level, rec)
+}
+
+func TestStructuredLoggerHijacked(t *testing.T) {
+	jsonBuff := bytes.NewBufferString("")
+	logfmtBuff := bytes.NewBufferString("")
+	n := nimble.New()
+	n.WithHandler(NewStructuredLogger(NewJSONSink(jsonBuff)))
+	n.WithHandler(NewStructuredLogger(NewLogfmtSink(logfmtBuff)))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.(http.Hijacker).Hijack()
+	})
+	n.ServeHTTP(hijackRecorder{httptest.NewRecorder()}, httptest.NewRequest("GET", "/ws", nil))
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(jsonBuff.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, entry["status"], float64(http.StatusSwitchingProtocols))
+	expect(t, entry["hijacked"], true)
+	expect(t, entry["size"], float64(0))
+	expect(t, strings.Contains(logfmtBuff.String(), " status=101 "), true)
+	expect(t, strings.Contains(logfmtBuff.String(), " hijacked=true\n"), true)
+}
@@ -1,51 +1,221 @@
 package nimware
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"html/template"
 	"log"
 	"net/http"
 	"os"
 	"runtime"
+	"strings"
+
+	"github.com/nimgo/nim/nimble"
 )
 
+// PanicHandler writes the response for a recovered panic. The incident ID of the panic
+// is available from the request with IncidentID.
+type PanicHandler func(w http.ResponseWriter, r *http.Request, recovered interface{}, stack []byte)
+
+// RecoveryOption configures a Recovery middleware.
+type RecoveryOption func(*Recovery)
+
+// RecoveryLogger sets the logger that panics are reported to.
+func RecoveryLogger(logger ALogger) RecoveryOption {
+	return func(rec *Recovery) {
+		rec.logger = logger
+	}
+}
+
+// RecoveryPrintStack sets whether the panic and stack trace are written to the response.
+func RecoveryPrintStack(printStack bool) RecoveryOption {
+	return func(rec *Recovery) {
+		rec.printStack = printStack
+	}
+}
+
+// RecoveryStackAll sets whether the stack traces of all goroutines are captured.
+func RecoveryStackAll(stackAll bool) RecoveryOption {
+	return func(rec *Recovery) {
+		rec.stackAll = stackAll
+	}
+}
+
+// RecoveryStackSize sets the maximum size in bytes of the captured stack trace.
+func RecoveryStackSize(stackSize int) RecoveryOption {
+	return func(rec *Recovery) {
+		rec.stackSize = stackSize
+	}
+}
+
+// RecoveryProduction hides the panic and stack trace from responses. They are still logged.
+func RecoveryProduction() RecoveryOption {
+	return RecoveryPrintStack(false)
+}
+
+// RecoveryPanicHandler sets the handler that writes the response for a recovered panic,
+// replacing the default JSON and HTML error pages.
+func RecoveryPanicHandler(handler PanicHandler) RecoveryOption {
+	return func(rec *Recovery) {
+		rec.panicHandler = handler
+	}
+}
+
 // NewRecovery returns a new instance of Recovery
-func NewRecovery() *Recovery {
-	return &Recovery{
+func NewRecovery(opts ...RecoveryOption) *Recovery {
+	rec := &Recovery{
 		logger:     log.New(os.Stdout, "[nr.] ", 0),
 		printStack: true,
 		stackAll:   false,
 		stackSize:  1024 * 8,
 	}
+	for _, opt := range opts {
+		opt(rec)
+	}
+	return rec
 }
 
 // Recovery is a middleware that attempts to recover from panics and writes a 500 if there was one.
+// Each panic is given an incident ID that is written to both the log and the response.
+// The response is JSON problem details (RFC 7807) for clients that accept JSON, and HTML otherwise.
+// If the response has already started, the panic is logged and the connection is aborted
+// with http.ErrAbortHandler instead. Panics with http.ErrAbortHandler are passed through.
 type Recovery struct {
-	logger     ALogger
-	printStack bool
-	stackAll   bool
-	stackSize  int
+	logger       ALogger
+	printStack   bool
+	stackAll     bool
+	stackSize    int
+	panicHandler PanicHandler
+}
+
+type incidentKey struct{}
+
+// IncidentID returns the incident ID of the panic being handled by a PanicHandler.
+func IncidentID(r *http.Request) string {
+	if r == nil {
+		return ""
+	}
+	id, _ := r.Context().Value(incidentKey{}).(string)
+	return id
 }
 
 func (rec *Recovery) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	r = withRequestIDHolder(r)
 	defer func() {
 		if err := recover(); err != nil {
-
-			if w.Header().Get("Content-Type") == "" {
-				w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			// a deliberate abort is not an application error
+			if err == http.ErrAbortHandler {
+				panic(err)
 			}
-			w.WriteHeader(http.StatusInternalServerError)
 
 			stack := make([]byte, rec.stackSize)
 			stack = stack[:runtime.Stack(stack, rec.stackAll)]
 
-			f := "RECOVER: %s\n%s"
-			rec.logger.Printf(f, err, stack)
+			id := newIncidentID()
+			requestID := GetRequestID(r)
+			if requestID != "" {
+				rec.logger.Printf("RECOVER: [%s] request %s: %s\n%s", id, requestID, err, stack)
+			} else {
+				rec.logger.Printf("RECOVER: [%s] %s\n%s", id, err, stack)
+			}
+
+			// the connection belongs to the handler once hijacked, so there is
+			// no response to write and aborting would not close it
+			if ww, ok := w.(nimble.Writer); ok && ww.Hijacked() {
+				rec.logger.Printf("RECOVER: [%s] connection hijacked, no response written", id)
+				return
+			}
+
+			// the status has already been sent, so abort the connection
+			// rather than corrupt the response with a second one
+			if ww, ok := w.(nimble.Writer); ok && ww.Written() {
+				rec.logger.Printf("RECOVER: [%s] response already started, aborting connection", id)
+				panic(http.ErrAbortHandler)
+			}
 
-			if rec.printStack {
-				fmt.Fprintf(w, f, err, stack)
+			if r != nil {
+				r = r.WithContext(context.WithValue(r.Context(), incidentKey{}, id))
 			}
+			if rec.panicHandler != nil {
+				rec.panicHandler(w, r, err, stack)
+				return
+			}
+			rec.writeError(w, r, id, requestID, err, stack)
 		}
 	}()
 
 	next(w, r)
 }
+
+// problem is the JSON problem details response for a recovered panic.
+type problem struct {
+	Type       string `json:"type"`
+	Title      string `json:"title"`
+	Status     int    `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	IncidentID string `json:"incident_id"`
+	RequestID  string `json:"request_id,omitempty"`
+	Stack      string `json:"stack,omitempty"`
+}
+
+func (rec *Recovery) writeError(w http.ResponseWriter, r *http.Request, id, requestID string, err interface{}, stack []byte) {
+	p := problem{
+		Type:       "about:blank",
+		Title:      http.StatusText(http.StatusInternalServerError),
+		Status:     http.StatusInternalServerError,
+		IncidentID: id,
+		RequestID:  requestID,
+	}
+	if rec.printStack {
+		p.Detail = fmt.Sprint(err)
+		p.Stack = string(stack)
+	}
+
+	w.Header().Set("X-Incident-Id", id)
+	w.Header().Del("Content-Length")
+	if acceptsJSON(r) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(p.Status)
+		json.NewEncoder(w).Encode(p)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(p.Status)
+	errorPage.Execute(w, p)
+}
+
+var errorPage = template.Must(template.New("recovery").Parse(`<!DOCTYPE html>
+<html>
+<head><title>{{.Status}} {{.Title}}</title></head>
+<body>
+<h1>{{.Title}}</h1>
+<p>Incident ID: <code>{{.IncidentID}}</code></p>
+{{if .RequestID}}<p>Request ID: <code>{{.RequestID}}</code></p>
+{{end}}{{if .Detail}}<p>{{.Detail}}</p>
+<pre>{{.Stack}}</pre>
+{{end}}</body>
+</html>
+`))
+
+func acceptsJSON(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	for _, accept := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(accept, ";", 2)[0])
+		if mediaType == "application/json" || mediaType == "application/problem+json" {
+			return true
+		}
+	}
+	return false
+}
+
+func newIncidentID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,10 +1,14 @@
 package nimware
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/json"
 	"log"
+	"net"
 	"net/http"
 	"reflect"
+	"strings"
 
 	"github.com/nimgo/nim/nimble"
 
@@ -25,6 +29,38 @@ func refute(t *testing.T, a interface{}, b interface{}) {
 	}
 }
 
+// serve runs the request through a nimble stack of the middleware followed by
+// handler, if it is not nil, and returns the recorded response.
+func serve(req *http.Request, handler http.HandlerFunc, middleware ...nimble.Handler) *httptest.ResponseRecorder {
+	n := nimble.New()
+	for _, m := range middleware {
+		n.WithHandler(m)
+	}
+	if handler != nil {
+		n.WithFunc(handler)
+	}
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, req)
+	return rec
+}
+
+// hello returns a handler that writes the status and a "hello" body.
+func hello(status int) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte("hello"))
+	}
+}
+
+// newRequest returns a request carrying the headers, given as name and value pairs.
+func newRequest(method, target string, header ...string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	return req
+}
+
 func TestRecovery(t *testing.T) {
 	buff := bytes.NewBufferString("")
 	rec := httptest.NewRecorder()
@@ -45,3 +81,114 @@ func TestRecovery(t *testing.T) {
 	refute(t, rec.Body.Len(), 0)
 	refute(t, len(buff.String()), 0)
 }
+
+func panicking(http.ResponseWriter, *http.Request) {
+	panic("here is a panic!")
+}
+
+func TestRecoveryJSON(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	req := newRequest("GET", "/", "Accept", "application/json, text/plain;q=0.5")
+	rec := serve(req, panicking, NewRecovery(RecoveryLogger(log.New(buff, "[n.] ", 0))))
+
+	var p problem
+	if err := json.Unmarshal(rec.Body.Bytes(), &p); err != nil {
+		t.Fatal(err)
+	}
+	expect(t, rec.Code, http.StatusInternalServerError)
+	expect(t, rec.Header().Get("Content-Type"), "application/problem+json")
+	expect(t, p.Status, http.StatusInternalServerError)
+	expect(t, p.Detail, "here is a panic!")
+	refute(t, p.Stack, "")
+	refute(t, p.IncidentID, "")
+	expect(t, rec.Header().Get("X-Incident-Id"), p.IncidentID)
+	expect(t, strings.Contains(buff.String(), p.IncidentID), true)
+}
+
+func TestRecoveryProduction(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	rec := serve(newRequest("GET", "/"), panicking, NewRecovery(RecoveryProduction(), RecoveryLogger(log.New(buff, "[n.] ", 0))))
+
+	expect(t, rec.Code, http.StatusInternalServerError)
+	expect(t, rec.Header().Get("Content-Type"), "text/html; charset=utf-8")
+	expect(t, strings.Contains(rec.Body.String(), "here is a panic!"), false)
+	expect(t, strings.Contains(rec.Body.String(), rec.Header().Get("X-Incident-Id")), true)
+	expect(t, strings.Contains(buff.String(), "here is a panic!"), true)
+}
+
+func TestRecoveryPanicHandler(t *testing.T) {
+	handler := func(w http.ResponseWriter, r *http.Request, recovered interface{}, stack []byte) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(IncidentID(r) + ":" + recovered.(string)))
+	}
+	rec := serve(newRequest("GET", "/"), panicking, NewRecovery(RecoveryPanicHandler(handler), RecoveryLogger(log.New(bytes.NewBuffer(nil), "", 0))))
+
+	expect(t, rec.Code, http.StatusServiceUnavailable)
+	expect(t, strings.HasSuffix(rec.Body.String(), ":here is a panic!"), true)
+	refute(t, strings.HasPrefix(rec.Body.String(), ":"), true)
+}
+
+func TestRecoveryAfterResponseStarted(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	rec := httptest.NewRecorder()
+
+	n := nimble.New().
+		WithHandler(NewRecovery(RecoveryLogger(log.New(buff, "[n.] ", 0)))).
+		WithFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("partial"))
+			panic("here is a panic!")
+		})
+
+	defer func() {
+		expect(t, recover(), http.ErrAbortHandler)
+		expect(t, rec.Code, http.StatusOK)
+		expect(t, rec.Body.String(), "partial")
+		expect(t, strings.Contains(buff.String(), "here is a panic!"), true)
+		expect(t, strings.Contains(buff.String(), "aborting connection"), true)
+	}()
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	t.Error("Expected the connection to be aborted")
+}
+
+func TestRecoveryRepanicsAbortHandler(t *testing.T) {
+	buff := bytes.NewBufferString("")
+
+	n := nimble.New().
+		WithHandler(NewRecovery(RecoveryLogger(log.New(buff, "[n.] ", 0)))).
+		WithFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic(http.ErrAbortHandler)
+		})
+
+	defer func() {
+		expect(t, recover(), http.ErrAbortHandler)
+		expect(t, buff.Len(), 0)
+	}()
+	n.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+}
+
+// hijackRecorder is a ResponseRecorder whose connection can be hijacked.
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+}
+
+func (h hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, nil
+}
+
+func TestRecoveryAfterHijack(t *testing.T) {
+	buff := bytes.NewBufferString("")
+	rec := hijackRecorder{httptest.NewRecorder()}
+
+	n := nimble.New().
+		WithHandler(NewRecovery(RecoveryLogger(log.New(buff, "[n.] ", 0)))).
+		WithFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.(http.Hijacker).Hijack()
+			panic("here is a panic!")
+		})
+
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	expect(t, rec.Body.Len(), 0)
+	expect(t, rec.Header().Get("X-Incident-Id"), "")
+	expect(t, strings.Contains(buff.String(), "here is a panic!"), true)
+	expect(t, strings.Contains(buff.String(), "connection hijacked"), true)
+}
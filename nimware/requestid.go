@@ -0,0 +1,138 @@
+package nimware
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+// RequestIDHeader is the header that carries the request ID.
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+// requestIDHolder holds the request ID in the request context. Middleware placed before
+// RequestID in the stack installs an empty holder, which RequestID fills in, so they can
+// find the ID once the rest of the stack has run.
+type requestIDHolder struct {
+	id string
+}
+
+// RequestIDOption configures a RequestID middleware.
+type RequestIDOption func(*RequestID)
+
+// RequestIDMaxLength sets the maximum length of an incoming request ID. Longer IDs are replaced.
+func RequestIDMaxLength(maxLength int) RequestIDOption {
+	return func(rid *RequestID) {
+		rid.maxLength = maxLength
+	}
+}
+
+// RequestIDGenerator sets the function that generates request IDs. Defaults to random UUIDs.
+func RequestIDGenerator(generate func() string) RequestIDOption {
+	return func(rid *RequestID) {
+		rid.generate = generate
+	}
+}
+
+// RequestIDTrustIncoming sets whether a valid X-Request-Id sent by the client is kept.
+// Defaults to true.
+func RequestIDTrustIncoming(trust bool) RequestIDOption {
+	return func(rid *RequestID) {
+		rid.trustIncoming = trust
+	}
+}
+
+// NewRequestID returns a new instance of RequestID
+func NewRequestID(opts ...RequestIDOption) *RequestID {
+	rid := &RequestID{
+		maxLength:     128,
+		generate:      newUUID,
+		trustIncoming: true,
+	}
+	for _, opt := range opts {
+		opt(rid)
+	}
+	return rid
+}
+
+// RequestID is a middleware that tags every request with an ID. A valid incoming
+// X-Request-Id header is kept, otherwise a new ID is generated. The ID is stored in the
+// request context, where GetRequestID finds it, and is returned to the client in the
+// X-Request-Id response header. Logger and Recovery include it when present.
+type RequestID struct {
+	maxLength     int
+	generate      func() string
+	trustIncoming bool
+}
+
+func (rid *RequestID) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	id := r.Header.Get(RequestIDHeader)
+	if !rid.trustIncoming || !rid.valid(id) {
+		id = rid.generate()
+	}
+
+	if ww, ok := w.(nimble.Writer); ok {
+		ww.Before(func(w nimble.Writer) {
+			w.Header().Set(RequestIDHeader, id)
+		})
+	} else {
+		w.Header().Set(RequestIDHeader, id)
+	}
+
+	r = withRequestIDHolder(r)
+	holderOf(r).id = id
+	next(w, r)
+}
+
+// valid reports whether id is an acceptable request ID: printable ASCII without spaces,
+// quotes or backslashes, and no longer than the maximum length.
+func (rid *RequestID) valid(id string) bool {
+	if id == "" || len(id) > rid.maxLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if c := id[i]; c <= ' ' || c > '~' || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// GetRequestID returns the ID of the request assigned by a RequestID middleware,
+// or "" if there is none. Logger and Recovery find the ID even when they are placed
+// before RequestID in the stack.
+func GetRequestID(r *http.Request) string {
+	if h := holderOf(r); h != nil {
+		return h.id
+	}
+	return ""
+}
+
+// withRequestIDHolder attaches a requestIDHolder to the request if it does not already carry one.
+func withRequestIDHolder(r *http.Request) *http.Request {
+	if r == nil || holderOf(r) != nil {
+		return r
+	}
+	return r.WithContext(context.WithValue(r.Context(), requestIDKey{}, &requestIDHolder{}))
+}
+
+func holderOf(r *http.Request) *requestIDHolder {
+	if r == nil {
+		return nil
+	}
+	h, _ := r.Context().Value(requestIDKey{}).(*requestIDHolder)
+	return h
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
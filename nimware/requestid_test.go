@@ -0,0 +1,91 @@
+package nimware
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestIDGenerated(t *testing.T) {
+	seen := ""
+	rec := serve(newRequest("GET", "/"), func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r)
+		w.WriteHeader(http.StatusOK)
+	}, NewRequestID())
+
+	expect(t, uuidPattern.MatchString(seen), true)
+	expect(t, rec.Header().Get(RequestIDHeader), seen)
+}
+
+func TestRequestIDIncoming(t *testing.T) {
+	seen := ""
+	capture := func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r)
+		w.WriteHeader(http.StatusOK)
+	}
+
+	rec := serve(newRequest("GET", "/", RequestIDHeader, "upstream-42"), capture, NewRequestID())
+	expect(t, seen, "upstream-42")
+	expect(t, rec.Header().Get(RequestIDHeader), "upstream-42")
+
+	serve(newRequest("GET", "/", RequestIDHeader, "bad id\"with quotes"), capture, NewRequestID())
+	expect(t, uuidPattern.MatchString(seen), true)
+
+	serve(newRequest("GET", "/", RequestIDHeader, "123456789"), capture, NewRequestID(RequestIDMaxLength(8)))
+	expect(t, uuidPattern.MatchString(seen), true)
+
+	serve(newRequest("GET", "/", RequestIDHeader, "upstream-42"), capture, NewRequestID(RequestIDTrustIncoming(false)))
+	refute(t, seen, "upstream-42")
+
+	serve(newRequest("GET", "/"), capture, NewRequestID(RequestIDGenerator(func() string { return "fixed" })))
+	expect(t, seen, "fixed")
+}
+
+func TestRequestIDInLoggerAndRecovery(t *testing.T) {
+	logBuff := bytes.NewBufferString("")
+	recBuff := bytes.NewBufferString("")
+
+	n := nimble.New()
+	n.WithHandler(NewStructuredLogger(NewLogfmtSink(logBuff)))
+	n.WithHandler(NewRecovery(RecoveryLogger(log.New(recBuff, "", 0))))
+	n.WithHandler(NewRequestID(RequestIDGenerator(func() string { return "req-1" })))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("here is a panic!")
+	})
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	expect(t, rec.Code, http.StatusInternalServerError)
+	expect(t, rec.Header().Get(RequestIDHeader), "req-1")
+	expect(t, strings.Contains(rec.Body.String(), "req-1"), true)
+	expect(t, strings.Contains(recBuff.String(), "request req-1: here is a panic!"), true)
+	expect(t, strings.Contains(logBuff.String(), "request_id=req-1"), true)
+}
+
+func TestRequestIDNotInstalled(t *testing.T) {
+	logBuff := bytes.NewBufferString("")
+	seen := "unset"
+
+	n := nimble.New()
+	n.WithHandler(NewStructuredLogger(NewLogfmtSink(logBuff)))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = GetRequestID(r)
+	})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set(RequestIDHeader, `spoofed "id" with spaces`)
+	n.ServeHTTP(httptest.NewRecorder(), req)
+
+	expect(t, seen, "")
+	expect(t, strings.Contains(logBuff.String(), "spoofed"), false)
+	expect(t, GetRequestID(req), "")
+}
@@ -0,0 +1,199 @@
+package nimware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogRecord describes a request served by the Logger.
+type LogRecord struct {
+	Start     time.Time
+	Method    string
+	Path      string
+	Query     string
+	Status    int
+	Size      int
+	Latency   time.Duration
+	ClientIP  string
+	UserAgent string
+	RequestID string
+	Proto     string
+	// RemoteUser is the user name given with basic authentication, if any.
+	RemoteUser string
+	// Header is the request header.
+	Header http.Header
+	// HeaderLatency is the time until the status and headers were written.
+	HeaderLatency time.Duration
+	// TTFB is the time until the first byte of the body was written.
+	TTFB time.Duration
+	// Streaming is the time from the first to the last write of the body. A long
+	// Streaming time against a short TTFB points at a slow client or a large body
+	// rather than a slow handler.
+	Streaming time.Duration
+	// Hijacked reports whether the handler took over the connection, as for a
+	// WebSocket upgrade. Status is then 101 unless the handler wrote another.
+	Hijacked bool
+}
+
+// URI returns the request path with its query string.
+func (rec *LogRecord) URI() string {
+	if rec.Query == "" {
+		return rec.Path
+	}
+	return rec.Path + "?" + rec.Query
+}
+
+// Sink receives one record for every request logged by a structured Logger.
+type Sink interface {
+	Log(level slog.Level, rec *LogRecord)
+}
+
+// LevelFunc returns the level to log a request at, given its response status.
+type LevelFunc func(status int) slog.Level
+
+// DefaultLevel logs server errors at error level, client errors at warn level
+// and everything else at info level.
+func DefaultLevel(status int) slog.Level {
+	switch {
+	case status >= 500:
+		return slog.LevelError
+	case status >= 400:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// field is a key/value pair of a LogRecord, in output order.
+type field struct {
+	key   string
+	value interface{}
+}
+
+func (rec *LogRecord) fields() []field {
+	return []field{
+		{"method", rec.Method},
+		{"path", rec.Path},
+		{"query", rec.Query},
+		{"status", rec.Status},
+		{"size", rec.Size},
+		{"latency_ms", float64(rec.Latency) / float64(time.Millisecond)},
+		{"header_ms", float64(rec.HeaderLatency) / float64(time.Millisecond)},
+		{"ttfb_ms", float64(rec.TTFB) / float64(time.Millisecond)},
+		{"streaming_ms", float64(rec.Streaming) / float64(time.Millisecond)},
+		{"client_ip", rec.ClientIP},
+		{"user_agent", rec.UserAgent},
+		{"request_id", rec.RequestID},
+		{"proto", rec.Proto},
+		{"hijacked", rec.Hijacked},
+	}
+}
+
+// writerSink serializes formatted records to an io.Writer.
+type writerSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format func(buf *bytes.Buffer, level slog.Level, rec *LogRecord)
+}
+
+func (s *writerSink) Log(level slog.Level, rec *LogRecord) {
+	var buf bytes.Buffer
+	s.format(&buf, level, rec)
+	buf.WriteByte('\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(buf.Bytes())
+}
+
+// NewJSONSink returns a Sink that writes one JSON object per line to w.
+func NewJSONSink(w io.Writer) Sink {
+	return &writerSink{w: w, format: formatJSON}
+}
+
+// NewLogfmtSink returns a Sink that writes one logfmt line per record to w.
+func NewLogfmtSink(w io.Writer) Sink {
+	return &writerSink{w: w, format: formatLogfmt}
+}
+
+func formatJSON(buf *bytes.Buffer, level slog.Level, rec *LogRecord) {
+	buf.WriteString(`{"time":`)
+	writeJSON(buf, rec.Start.Format(time.RFC3339Nano))
+	buf.WriteString(`,"level":`)
+	writeJSON(buf, level.String())
+	for _, f := range rec.fields() {
+		buf.WriteByte(',')
+		writeJSON(buf, f.key)
+		buf.WriteByte(':')
+		writeJSON(buf, f.value)
+	}
+	buf.WriteByte('}')
+}
+
+func writeJSON(buf *bytes.Buffer, v interface{}) {
+	b, _ := json.Marshal(v)
+	buf.Write(b)
+}
+
+func formatLogfmt(buf *bytes.Buffer, level slog.Level, rec *LogRecord) {
+	buf.WriteString("time=")
+	buf.WriteString(rec.Start.Format(time.RFC3339Nano))
+	buf.WriteString(" level=")
+	buf.WriteString(level.String())
+	for _, f := range rec.fields() {
+		buf.WriteByte(' ')
+		buf.WriteString(f.key)
+		buf.WriteByte('=')
+		switch v := f.value.(type) {
+		case string:
+			writeLogfmtString(buf, v)
+		case int:
+			buf.WriteString(strconv.Itoa(v))
+		case float64:
+			buf.WriteString(strconv.FormatFloat(v, 'f', 3, 64))
+		case bool:
+			buf.WriteString(strconv.FormatBool(v))
+		}
+	}
+}
+
+func writeLogfmtString(buf *bytes.Buffer, s string) {
+	if s == "" || strings.ContainsAny(s, " =\"\\") || strings.IndexFunc(s, func(r rune) bool { return r < ' ' }) >= 0 {
+		buf.WriteString(strconv.Quote(s))
+		return
+	}
+	buf.WriteString(s)
+}
+
+// slogSink adapts a *slog.Logger to a Sink.
+type slogSink struct {
+	logger *slog.Logger
+}
+
+// NewSlogSink returns a Sink that logs records to the slog.Logger, with
+// the request fields as attributes.
+func NewSlogSink(logger *slog.Logger) Sink {
+	return &slogSink{logger}
+}
+
+func (s *slogSink) Log(level slog.Level, rec *LogRecord) {
+	ctx := context.Background()
+	if !s.logger.Enabled(ctx, level) {
+		return
+	}
+
+	fields := rec.fields()
+	attrs := make([]slog.Attr, len(fields))
+	for i, f := range fields {
+		attrs[i] = slog.Any(f.key, f.value)
+	}
+	s.logger.LogAttrs(ctx, level, "request", attrs...)
+}
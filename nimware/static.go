@@ -1,31 +1,146 @@
 package nimware
 
 import (
+	"bytes"
+	"io"
+	"mime"
 	"net/http"
+	"os"
 	"path"
 	"strings"
 )
 
+// StaticOption configures a Static middleware.
+type StaticOption func(*Static)
+
+// StaticPrefix sets the URL prefix the directory is served under, such as "/public".
+func StaticPrefix(prefix string) StaticOption {
+	return func(s *Static) {
+		s.prefix = prefix
+	}
+}
+
+// StaticIndexFiles sets the files tried in order when a directory is requested.
+func StaticIndexFiles(files ...string) StaticOption {
+	return func(s *Static) {
+		s.indexFiles = files
+	}
+}
+
+// StaticDirFallthrough sets whether a directory without an index file is passed
+// to the next middleware. Otherwise a 404 is written. Defaults to true.
+func StaticDirFallthrough(fallThrough bool) StaticOption {
+	return func(s *Static) {
+		s.dirFallThrough = fallThrough
+	}
+}
+
+// StaticRedirectSlash sets whether a directory requested without a trailing slash
+// is redirected to the path with one. Defaults to true.
+func StaticRedirectSlash(redirect bool) StaticOption {
+	return func(s *Static) {
+		s.redirectSlash = redirect
+	}
+}
+
+// StaticPrecompressed serves precompressed sidecar files, such as app.js.br and app.js.gz
+// next to app.js, to clients that accept their coding. The codings are tried in order
+// when the client accepts them equally, and default to "br" and "gzip".
+// Supported codings are br (.br), gzip (.gz) and zstd (.zst).
+func StaticPrecompressed(codings ...string) StaticOption {
+	if len(codings) == 0 {
+		codings = []string{"br", "gzip"}
+	}
+	return func(s *Static) {
+		s.precompressed = nil
+		for _, coding := range codings {
+			if _, ok := sidecarExtensions[coding]; ok {
+				s.precompressed = append(s.precompressed, coding)
+			}
+		}
+	}
+}
+
+// sidecarExtensions maps content codings to the extension of their sidecar files.
+var sidecarExtensions = map[string]string{
+	"br":   ".br",
+	"gzip": ".gz",
+	"zstd": ".zst",
+}
+
+// StaticFallthrough sets whether requests for missing files are passed to the next
+// middleware. Setting it to false makes Static a terminal file server that writes
+// a 404 instead. Defaults to true.
+func StaticFallthrough(fallThrough bool) StaticOption {
+	return func(s *Static) {
+		s.fallThrough = fallThrough
+	}
+}
+
+// StaticSPA enables single-page-app mode: GET and HEAD requests for missing paths
+// that accept HTML and have no file extension are answered with the fallback file,
+// such as "/index.html", instead of falling through. Paths with an extension are
+// treated as missing assets and handled as usual.
+func StaticSPA(fallback string) StaticOption {
+	return func(s *Static) {
+		s.spaFallback = fallback
+	}
+}
+
+// StaticSPAExclude sets path prefixes, such as "/api", that are never answered
+// with the single-page-app fallback and pass to the next middleware instead.
+func StaticSPAExclude(prefixes ...string) StaticOption {
+	return func(s *Static) {
+		s.spaExclude = prefixes
+	}
+}
+
 // NewStatic returns a new instance of Static
-func NewStatic(directory http.FileSystem) *Static {
-	return &Static{
-		dir:       directory,
-		prefix:    "",
-		indexFile: "index.html",
+func NewStatic(directory http.FileSystem, opts ...StaticOption) *Static {
+	s := &Static{
+		dir:            directory,
+		prefix:         "",
+		indexFiles:     []string{"index.html"},
+		dirFallThrough: true,
+		redirectSlash:  true,
+		fallThrough:    true,
+		etag:           true,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // Static is a middleware to serves static files in the given directory/filesystem.
 // If the file does not exist on the filesystem, it passes along to the next middleware
-// in the chain. If you desire "fileserver" type behavior where it returns
-// a 404 for unfound files, you should consider using http.FileServer from the Go stdlib.
+// in the chain, unless it is configured as a terminal file server with StaticFallthrough.
 type Static struct {
 	// Dir is the directory to serve static files from
 	dir http.FileSystem
 	// Prefix is the optional prefix used to serve the static directory content
 	prefix string
-	// IndexFile defines which file to serve as index if it exists.
-	indexFile string
+	// IndexFiles defines which files to try, in order, to serve as index.
+	indexFiles []string
+	// DirFallThrough passes directories without an index file to the next middleware.
+	dirFallThrough bool
+	// RedirectSlash redirects directories requested without a trailing slash.
+	redirectSlash bool
+	// FallThrough passes requests for missing files to the next middleware.
+	fallThrough bool
+	// Precompressed lists the codings of sidecar files to look for.
+	precompressed []string
+	// SPAFallback is the file served for unknown app routes in single-page-app mode.
+	spaFallback string
+	// SPAExclude lists path prefixes that never get the single-page-app fallback.
+	spaExclude []string
+	// CacheRules set the Cache-Control header of matching files.
+	cacheRules []cacheRule
+	// ETag enables content-hash ETags, which are kept in etags.
+	etag  bool
+	etags etagCache
+	// Memory holds the content of small files, if enabled.
+	memory memoryCache
 }
 
 func (s *Static) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
@@ -48,45 +163,170 @@ func (s *Static) ServeHTTP(rw http.ResponseWriter, r *http.Request, next http.Ha
 		}
 	}
 
-	f, err := s.dir.Open(file)
+	f, fi, err := s.open(file)
 	if err != nil {
-		// Handle multiple requests from modern browsers if missing the favicon.ico
-		if file != "/favicon.ico" {
-			next(rw, r)
-		}
-		// discard the error?
+		s.miss(rw, r, next, file)
 		return
 	}
 	defer f.Close()
 
-	fi, err := f.Stat()
-	if err != nil {
-		next(rw, r)
-		return
-	}
-
 	// try to serve index file
 	if fi.IsDir() {
 		// redirect if missing trailing slash
-		if !strings.HasSuffix(r.URL.Path, "/") {
+		if s.redirectSlash && !strings.HasSuffix(r.URL.Path, "/") {
 			http.Redirect(rw, r, r.URL.Path+"/", http.StatusFound)
 			return
 		}
 
-		file = path.Join(file, s.indexFile)
-		f, err = s.dir.Open(file)
-		if err != nil {
-			next(rw, r)
+		f, fi, file = s.index(file)
+		if f == nil {
+			if s.dirFallThrough {
+				next(rw, r)
+			} else {
+				http.NotFound(rw, r)
+			}
 			return
 		}
 		defer f.Close()
+	}
 
-		fi, err = f.Stat()
-		if err != nil || fi.IsDir() {
-			next(rw, r)
-			return
+	s.serveFile(rw, r, file, f, fi)
+}
+
+// serveFile writes the file with its caching headers, or a precompressed sidecar of it.
+func (s *Static) serveFile(rw http.ResponseWriter, r *http.Request, file string, f http.File, fi os.FileInfo) {
+	s.setCacheControl(rw, file)
+	if len(s.precompressed) > 0 && s.serveSidecar(rw, r, file, f) {
+		return
+	}
+	content := s.content(file, f, fi)
+	s.setETag(rw, file, content, fi)
+	http.ServeContent(rw, r, file, fi.ModTime(), content)
+}
+
+// content returns the content of the file, from memory if it is cached there.
+func (s *Static) content(file string, f http.File, fi os.FileInfo) io.ReadSeeker {
+	if s.memory.maxSize > 0 {
+		if data, ok := s.memory.get(file, f, fi); ok {
+			return bytes.NewReader(data)
 		}
 	}
+	return f
+}
+
+// serveSidecar serves a precompressed sidecar of the file in the best coding
+// accepted by the client, and reports whether it found one.
+func (s *Static) serveSidecar(rw http.ResponseWriter, r *http.Request, file string, f http.File) bool {
+	addVary(rw.Header(), "Accept-Encoding")
+
+	for _, coding := range acceptedEncodings(r.Header.Get("Accept-Encoding"), s.precompressed) {
+		sidecar := file + sidecarExtensions[coding]
+		cf, cfi, err := s.open(sidecar)
+		if err != nil {
+			continue
+		}
+		if cfi.IsDir() {
+			cf.Close()
+			continue
+		}
+		defer cf.Close()
+
+		// the content type is that of the original file, not of the sidecar
+		ctype := mime.TypeByExtension(path.Ext(file))
+		if ctype == "" {
+			var buf [512]byte
+			n, _ := io.ReadFull(f, buf[:])
+			ctype = http.DetectContentType(buf[:n])
+		}
+		rw.Header().Set("Content-Type", ctype)
+		rw.Header().Set("Content-Encoding", coding)
+		content := s.content(sidecar, cf, cfi)
+		s.setETag(rw, sidecar, content, cfi)
+		http.ServeContent(rw, r, file, cfi.ModTime(), content)
+		return true
+	}
+	return false
+}
+
+// open opens the file and returns it with its FileInfo.
+func (s *Static) open(file string) (http.File, os.FileInfo, error) {
+	f, err := s.dir.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return f, fi, nil
+}
 
-	http.ServeContent(rw, r, file, fi.ModTime(), f)
+// index opens the first index file that exists in the directory.
+func (s *Static) index(dir string) (http.File, os.FileInfo, string) {
+	for _, index := range s.indexFiles {
+		file := path.Join(dir, index)
+		f, fi, err := s.open(file)
+		if err != nil {
+			continue
+		}
+		if fi.IsDir() {
+			f.Close()
+			continue
+		}
+		return f, fi, file
+	}
+	return nil, nil, ""
+}
+
+// miss handles a request for a file that does not exist.
+func (s *Static) miss(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc, file string) {
+	if s.spaRoute(r, file) {
+		if f, fi, err := s.open(s.spaFallback); err == nil {
+			defer f.Close()
+			if !fi.IsDir() {
+				s.serveFile(rw, r, s.spaFallback, f, fi)
+				return
+			}
+		}
+	}
+	if !s.fallThrough {
+		http.NotFound(rw, r)
+		return
+	}
+	// Handle multiple requests from modern browsers if missing the favicon.ico
+	if file != "/favicon.ico" {
+		next(rw, r)
+	}
+}
+
+// spaRoute reports whether the request for a missing file should be answered
+// with the single-page-app fallback.
+func (s *Static) spaRoute(r *http.Request, file string) bool {
+	if s.spaFallback == "" || r.Method != "GET" && r.Method != "HEAD" {
+		return false
+	}
+	if path.Ext(file) != "" || !acceptsHTML(r) {
+		return false
+	}
+	for _, prefix := range s.spaExclude {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if p := r.URL.Path; p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return false
+		}
+	}
+	return true
+}
+
+// acceptsHTML reports whether the Accept header lists an HTML media type,
+// as browsers do when navigating.
+func acceptsHTML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		if strings.EqualFold(mediaType, "text/html") || strings.EqualFold(mediaType, "application/xhtml+xml") {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,131 @@
+package nimware
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache-Control values for use with StaticCacheControl.
+const (
+	// CacheImmutable caches a response for a year without revalidation. It suits
+	// fingerprinted assets whose name changes whenever their content does.
+	CacheImmutable = "public, max-age=31536000, immutable"
+	// CacheNoCache makes clients revalidate a response before every use.
+	CacheNoCache = "no-cache"
+)
+
+// StaticCacheControl sets the Cache-Control header for files matching the pattern.
+// Patterns use path.Match syntax and are matched against the file name, such as
+// "*.css" or "index.html", or against the full path if they contain a slash,
+// such as "/fonts/*". The first matching rule applies.
+func StaticCacheControl(pattern, cacheControl string) StaticOption {
+	return StaticCacheRule(func(file string) bool {
+		name := path.Base(file)
+		if strings.Contains(pattern, "/") {
+			name = file
+		}
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}, cacheControl)
+}
+
+// StaticCacheRule sets the Cache-Control header for files matched by the predicate.
+// Fingerprinted can be used to match assets such as app.3f2a9c1b.js.
+func StaticCacheRule(match func(file string) bool, cacheControl string) StaticOption {
+	return func(s *Static) {
+		s.cacheRules = append(s.cacheRules, cacheRule{match, cacheControl})
+	}
+}
+
+// StaticETag sets whether strong ETags are computed from the content of files.
+// Hashes are computed once and kept in memory until the file changes. Defaults to true.
+// Files without a modification time always get an ETag.
+func StaticETag(etag bool) StaticOption {
+	return func(s *Static) {
+		s.etag = etag
+	}
+}
+
+var fingerprint = regexp.MustCompile(`[.-][0-9a-fA-F]{8,}\.[^./]+$`)
+
+// Fingerprinted reports whether the file name carries a content hash, as in
+// app.3f2a9c1b.js or app-3f2a9c1b.css.
+func Fingerprinted(file string) bool {
+	return fingerprint.MatchString(path.Base(file))
+}
+
+type cacheRule struct {
+	match        func(file string) bool
+	cacheControl string
+}
+
+// etagEntry is a computed ETag, valid while the file keeps its size and modification time.
+type etagEntry struct {
+	size    int64
+	modTime time.Time
+	etag    string
+}
+
+// etagCache holds the ETags of files that have been served.
+type etagCache struct {
+	mu      sync.RWMutex
+	entries map[string]etagEntry
+}
+
+// get returns the ETag of the file, hashing its content if it is not cached or has changed.
+// The file is rewound after hashing.
+func (c *etagCache) get(file string, f io.ReadSeeker, fi os.FileInfo) (string, error) {
+	c.mu.RLock()
+	entry, ok := c.entries[file]
+	c.mu.RUnlock()
+	if ok && entry.size == fi.Size() && entry.modTime.Equal(fi.ModTime()) {
+		return entry.etag, nil
+	}
+
+	h := sha256.New()
+	_, err := io.Copy(h, f)
+	if _, serr := f.Seek(0, io.SeekStart); err == nil {
+		err = serr
+	}
+	if err != nil {
+		return "", err
+	}
+	etag := `"` + base64.RawURLEncoding.EncodeToString(h.Sum(nil)[:16]) + `"`
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[string]etagEntry{}
+	}
+	c.entries[file] = etagEntry{fi.Size(), fi.ModTime(), etag}
+	c.mu.Unlock()
+	return etag, nil
+}
+
+// setCacheControl sets the Cache-Control header of the first rule matching the file.
+func (s *Static) setCacheControl(rw http.ResponseWriter, file string) {
+	for _, rule := range s.cacheRules {
+		if rule.match(file) {
+			rw.Header().Set("Cache-Control", rule.cacheControl)
+			return
+		}
+	}
+}
+
+// setETag sets the ETag header from the content of the file, if enabled or if the
+// file has no modification time to validate it with, as is the case for embedded files.
+func (s *Static) setETag(rw http.ResponseWriter, file string, f io.ReadSeeker, fi os.FileInfo) {
+	if !s.etag && !fi.ModTime().IsZero() {
+		return
+	}
+	if etag, err := s.etags.get(file, f, fi); err == nil {
+		rw.Header().Set("ETag", etag)
+	}
+}
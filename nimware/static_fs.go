@@ -0,0 +1,74 @@
+package nimware
+
+import (
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// NewStaticFS returns a new instance of Static serving the files of an fs.FS, such
+// as an embed.FS. Use fs.Sub to serve a sub-directory as the root:
+//
+//	public, _ := fs.Sub(assets, "public")
+//	nimware.NewStaticFS(public, nimware.StaticPrefix("/public"))
+//
+// Embedded files have no modification time, so they are validated with
+// content-hash ETags only, which are computed even when StaticETag is disabled.
+func NewStaticFS(fsys fs.FS, opts ...StaticOption) *Static {
+	return NewStatic(http.FS(fsys), opts...)
+}
+
+// StaticMemoryCache keeps the content of files up to maxSize bytes in memory once
+// they have been served, so later requests are served without reading the file.
+// Cached content is dropped when the size or modification time of the file changes.
+func StaticMemoryCache(maxSize int64) StaticOption {
+	return func(s *Static) {
+		s.memory.maxSize = maxSize
+	}
+}
+
+// memoryEntry is the cached content of a file with the size and modification time it was read at.
+type memoryEntry struct {
+	size    int64
+	modTime time.Time
+	data    []byte
+}
+
+// memoryCache holds the content of small files that have been served.
+type memoryCache struct {
+	maxSize int64
+	mu      sync.RWMutex
+	entries map[string]memoryEntry
+}
+
+// get returns the content of the file, reading it if it is small enough and not
+// cached or has changed. It reports false for files that are not cached.
+func (c *memoryCache) get(file string, f http.File, fi os.FileInfo) ([]byte, bool) {
+	if fi.Size() > c.maxSize {
+		return nil, false
+	}
+
+	c.mu.RLock()
+	entry, ok := c.entries[file]
+	c.mu.RUnlock()
+	if ok && entry.size == fi.Size() && entry.modTime.Equal(fi.ModTime()) {
+		return entry.data, true
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		f.Seek(0, io.SeekStart)
+		return nil, false
+	}
+
+	c.mu.Lock()
+	if c.entries == nil {
+		c.entries = map[string]memoryEntry{}
+	}
+	c.entries[file] = memoryEntry{fi.Size(), fi.ModTime(), data}
+	c.mu.Unlock()
+	return data, true
+}
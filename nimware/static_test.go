@@ -2,7 +2,13 @@ package nimware
 
 import (
 	"bytes"
+	"io/fs"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing/fstest"
+	"time"
 
 	"net/http/httptest"
 	"testing"
@@ -88,9 +94,7 @@ func TestStaticOptionsServeIndex(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	n := nimble.New()
-	s := NewStatic(http.Dir("."))
-	s.indexFile = "nimble.go"
-	n.WithHandler(s)
+	n.WithHandler(NewStatic(http.Dir("."), StaticIndexFiles("missing.html", "static.go")))
 
 	req, err := http.NewRequest("GET", "http://localhost:3000/", nil)
 	if err != nil {
@@ -105,9 +109,7 @@ func TestStaticOptionsPrefix(t *testing.T) {
 	rec := httptest.NewRecorder()
 
 	n := nimble.New()
-	s := NewStatic(http.Dir("."))
-	s.prefix = "/public"
-	n.WithHandler(s)
+	n.WithHandler(NewStatic(http.Dir("."), StaticPrefix("/public")))
 
 	// Check file content behaviour
 	req, err := http.NewRequest("GET", "http://localhost:3000/public/static_test.go", nil)
@@ -118,3 +120,263 @@ func TestStaticOptionsPrefix(t *testing.T) {
 	n.ServeHTTP(rec, req)
 	expect(t, rec.Code, http.StatusOK)
 }
+
+func TestStaticOptionsFallthrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	reached := false
+
+	n := nimble.New()
+	n.WithHandler(NewStatic(http.Dir("."), StaticFallthrough(false)))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	})
+
+	req, err := http.NewRequest("GET", "http://localhost:3000/missing.go", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Code, http.StatusNotFound)
+	expect(t, reached, false)
+}
+
+func TestStaticOptionsDirFallthrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	reached := false
+
+	n := nimble.New()
+	n.WithHandler(NewStatic(http.Dir("."), StaticDirFallthrough(false)))
+	n.WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		reached = true
+	})
+
+	req, err := http.NewRequest("GET", "http://localhost:3000/", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Code, http.StatusNotFound)
+	expect(t, reached, false)
+}
+
+func TestStaticOptionsRedirectSlash(t *testing.T) {
+	rec := httptest.NewRecorder()
+
+	n := nimble.New()
+	n.WithHandler(NewStatic(http.Dir(".."), StaticPrefix("/src")))
+
+	req, err := http.NewRequest("GET", "http://localhost:3000/src/nimware", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Code, http.StatusFound)
+	expect(t, rec.Header().Get("Location"), "/src/nimware/")
+
+	rec = httptest.NewRecorder()
+	n = nimble.New()
+	n.WithHandler(NewStatic(http.Dir(".."), StaticPrefix("/src"), StaticRedirectSlash(false), StaticIndexFiles("static.go")))
+
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Code, http.StatusOK)
+}
+
+func TestStaticCacheControl(t *testing.T) {
+	n := nimble.New()
+	n.WithHandler(NewStatic(http.Dir("."),
+		StaticCacheRule(Fingerprinted, CacheImmutable),
+		StaticCacheControl("static_test.go", CacheNoCache),
+		StaticCacheControl("/*.go", "public, max-age=60"),
+	))
+
+	for file, cacheControl := range map[string]string{
+		"/static_test.go": CacheNoCache,
+		"/static.go":      "public, max-age=60",
+	} {
+		rec := httptest.NewRecorder()
+		n.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost:3000"+file, nil))
+		expect(t, rec.Code, http.StatusOK)
+		expect(t, rec.Header().Get("Cache-Control"), cacheControl)
+	}
+
+	expect(t, Fingerprinted("/js/app.3f2a9c1b.js"), true)
+	expect(t, Fingerprinted("/css/site-3f2a9c1be0.min.css"), false)
+	expect(t, Fingerprinted("/css/site.min-3f2a9c1be0.css"), true)
+	expect(t, Fingerprinted("/js/app.js"), false)
+}
+
+func TestStaticETag(t *testing.T) {
+	n := nimble.New()
+	n.WithHandler(NewStatic(http.Dir(".")))
+
+	rec := httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost:3000/static_test.go", nil))
+	etag := rec.Header().Get("ETag")
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, strings.HasPrefix(etag, `"`), true)
+	refute(t, rec.Body.Len(), 0)
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "http://localhost:3000/static_test.go", nil)
+	req.Header.Set("If-None-Match", etag)
+	n.ServeHTTP(rec, req)
+	expect(t, rec.Code, http.StatusNotModified)
+	expect(t, rec.Body.Len(), 0)
+	expect(t, rec.Header().Get("ETag"), etag)
+
+	rec = httptest.NewRecorder()
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost:3000/static.go", nil))
+	refute(t, rec.Header().Get("ETag"), etag)
+
+	rec = httptest.NewRecorder()
+	n = nimble.New()
+	n.WithHandler(NewStatic(http.Dir("."), StaticETag(false)))
+	n.ServeHTTP(rec, httptest.NewRequest("GET", "http://localhost:3000/static_test.go", nil))
+	expect(t, rec.Header().Get("ETag"), "")
+}
+
+func precompressedDir(t *testing.T) http.Dir {
+	dir := t.TempDir()
+	files := map[string]string{
+		"app.js":         "console.log('plain')",
+		"app.js.gz":      "gzip-bytes",
+		"app.js.br":      "brotli-bytes",
+		"README":         "<html><body>readme</body></html>",
+		"README.gz":      "gzip-readme",
+		"plain.css":      "body {}",
+		"compressed.txt": "only gzip",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return http.Dir(dir)
+}
+
+func TestStaticPrecompressed(t *testing.T) {
+	s := NewStatic(precompressedDir(t), StaticPrecompressed())
+
+	rec := serve(newRequest("GET", "/app.js", "Accept-Encoding", "gzip, br"), nil, s)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Content-Encoding"), "br")
+	expect(t, rec.Header().Get("Content-Type"), "text/javascript; charset=utf-8")
+	expect(t, rec.Header().Get("Vary"), "Accept-Encoding")
+	expect(t, rec.Body.String(), "brotli-bytes")
+	brETag := rec.Header().Get("ETag")
+
+	rec = serve(newRequest("GET", "/app.js", "Accept-Encoding", "gzip"), nil, s)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Body.String(), "gzip-bytes")
+	refute(t, rec.Header().Get("ETag"), brETag)
+
+	rec = serve(newRequest("GET", "/app.js"), nil, s)
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+	expect(t, rec.Header().Get("Vary"), "Accept-Encoding")
+	expect(t, rec.Body.String(), "console.log('plain')")
+
+	rec = serve(newRequest("GET", "/README", "Accept-Encoding", "gzip"), nil, s)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Header().Get("Content-Type"), "text/html; charset=utf-8")
+
+	rec = serve(newRequest("GET", "/plain.css", "Accept-Encoding", "gzip, br"), nil, s)
+	expect(t, rec.Header().Get("Content-Encoding"), "")
+	expect(t, rec.Body.String(), "body {}")
+}
+
+func TestStaticPrecompressedRangeAndHead(t *testing.T) {
+	s := NewStatic(precompressedDir(t), StaticPrecompressed("gzip"))
+
+	rec := serve(newRequest("GET", "/app.js", "Accept-Encoding", "gzip", "Range", "bytes=0-3"), nil, s)
+	expect(t, rec.Code, http.StatusPartialContent)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Body.String(), "gzip")
+
+	rec = serve(newRequest("HEAD", "/app.js", "Accept-Encoding", "br, gzip"), nil, s)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Header().Get("Content-Encoding"), "gzip")
+	expect(t, rec.Body.Len(), 0)
+}
+
+func TestStaticSPA(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html>app</html>"), 0644)
+	os.WriteFile(filepath.Join(dir, "app.js"), []byte("app()"), 0644)
+
+	s := NewStatic(http.Dir(dir), StaticSPA("/index.html"), StaticSPAExclude("/api"))
+	teapot := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}
+	html := "text/html,application/xhtml+xml;q=0.9,*/*;q=0.8"
+
+	rec := serve(newRequest("GET", "/users/42", "Accept", html), teapot, s)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "<html>app</html>")
+	expect(t, rec.Header().Get("Content-Type"), "text/html; charset=utf-8")
+
+	rec = serve(newRequest("GET", "/app.js", "Accept", html), teapot, s)
+	expect(t, rec.Body.String(), "app()")
+
+	// missing assets, API routes, non-HTML and non-GET requests fall through
+	expect(t, serve(newRequest("GET", "/missing.js", "Accept", html), teapot, s).Code, http.StatusTeapot)
+	expect(t, serve(newRequest("GET", "/api/users", "Accept", html), teapot, s).Code, http.StatusTeapot)
+	expect(t, serve(newRequest("GET", "/api", "Accept", html), teapot, s).Code, http.StatusTeapot)
+	expect(t, serve(newRequest("GET", "/apiary", "Accept", html), teapot, s).Code, http.StatusOK)
+	expect(t, serve(newRequest("GET", "/users/42", "Accept", "application/json"), teapot, s).Code, http.StatusTeapot)
+	expect(t, serve(newRequest("POST", "/users/42", "Accept", html), teapot, s).Code, http.StatusTeapot)
+}
+
+func TestStaticFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"public/index.html": {Data: []byte("<html>home</html>")},
+		"public/app.js":     {Data: []byte("app()")},
+		"private.txt":       {Data: []byte("secret")},
+	}
+	public, err := fs.Sub(fsys, "public")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewStaticFS(public, StaticETag(false))
+
+	rec := serve(newRequest("GET", "/"), nil, s)
+	expect(t, rec.Code, http.StatusOK)
+	expect(t, rec.Body.String(), "<html>home</html>")
+
+	rec = serve(newRequest("GET", "/app.js"), nil, s)
+	expect(t, rec.Body.String(), "app()")
+	expect(t, rec.Header().Get("Last-Modified"), "")
+	etag := rec.Header().Get("ETag")
+	refute(t, etag, "")
+
+	rec = serve(newRequest("GET", "/app.js", "If-None-Match", etag), nil, s)
+	expect(t, rec.Code, http.StatusNotModified)
+
+	rec = serve(newRequest("GET", "/private.txt"), nil, s)
+	expect(t, rec.Body.Len(), 0)
+}
+
+func TestStaticMemoryCache(t *testing.T) {
+	modTime := time.Now()
+	fsys := fstest.MapFS{
+		"small.txt": {Data: []byte("small"), ModTime: modTime},
+		"large.txt": {Data: []byte("large content"), ModTime: modTime},
+	}
+	s := NewStaticFS(fsys, StaticMemoryCache(8))
+
+	expect(t, serve(newRequest("GET", "/small.txt"), nil, s).Body.String(), "small")
+	expect(t, serve(newRequest("GET", "/large.txt"), nil, s).Body.String(), "large content")
+
+	// same size and modification time: the cached content is served
+	fsys["small.txt"].Data = []byte("SMALL")
+	fsys["large.txt"].Data = []byte("LARGE CONTENT")
+	expect(t, serve(newRequest("GET", "/small.txt"), nil, s).Body.String(), "small")
+	expect(t, serve(newRequest("GET", "/large.txt"), nil, s).Body.String(), "LARGE CONTENT")
+
+	fsys["small.txt"].ModTime = modTime.Add(time.Second)
+	rec := serve(newRequest("GET", "/small.txt", "Range", "bytes=1-2"), nil, s)
+	expect(t, rec.Code, http.StatusPartialContent)
+	expect(t, rec.Body.String(), "MA")
+}
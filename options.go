@@ -0,0 +1,79 @@
+package nim
+
+import (
+	"log"
+	"time"
+)
+
+// Option configures the Server created by RunWith.
+type Option func(*Server)
+
+// Addr sets the address to listen on, in the same format as http.ListenAndServe.
+// If it is not set, the PORT environment variable or the default address is used.
+func Addr(addr string) Option {
+	return func(s *Server) {
+		s.Addr = detectAddress(addr)
+	}
+}
+
+// ReadTimeout sets the maximum duration for reading the entire request, including the body.
+func ReadTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.ReadTimeout = d
+	}
+}
+
+// ReadHeaderTimeout sets the amount of time allowed to read the request headers.
+func ReadHeaderTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.ReadHeaderTimeout = d
+	}
+}
+
+// WriteTimeout sets the maximum duration before timing out writes of the response.
+func WriteTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.WriteTimeout = d
+	}
+}
+
+// IdleTimeout sets the maximum amount of time to wait for the next request
+// when keep-alives are enabled.
+func IdleTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.IdleTimeout = d
+	}
+}
+
+// MaxHeaderBytes sets the maximum number of bytes the server will read
+// parsing the request header's keys and values, including the request line.
+func MaxHeaderBytes(n int) Option {
+	return func(s *Server) {
+		s.MaxHeaderBytes = n
+	}
+}
+
+// ErrorLog sets the logger for errors accepting connections, unexpected
+// behavior from handlers, and underlying FileSystem errors.
+func ErrorLog(l *log.Logger) Option {
+	return func(s *Server) {
+		s.ErrorLog = l
+	}
+}
+
+// ShutdownTimeout sets the time allowed for in-flight requests to complete
+// when the server shuts down.
+func ShutdownTimeout(d time.Duration) Option {
+	return func(s *Server) {
+		s.ShutdownTimeout = d
+	}
+}
+
+// TLS makes the server listen for HTTPS connections using the certificate
+// and matching private key files.
+func TLS(certFile, keyFile string) Option {
+	return func(s *Server) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
@@ -0,0 +1,142 @@
+package nim
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeSelfSignedCert generates a certificate for 127.0.0.1 and returns the paths
+// to the certificate and key files.
+func writeSelfSignedCert(t *testing.T) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"nim test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestServerOptions(t *testing.T) {
+	errorLog := log.New(os.Stderr, "", 0)
+	s := NewServer(New()).Configure(
+		Addr(":6060"),
+		ReadTimeout(time.Second),
+		ReadHeaderTimeout(2*time.Second),
+		WriteTimeout(3*time.Second),
+		IdleTimeout(4*time.Second),
+		MaxHeaderBytes(1<<10),
+		ErrorLog(errorLog),
+		ShutdownTimeout(5*time.Second),
+	)
+
+	expect(t, s.Addr, ":6060")
+	expect(t, s.ReadTimeout, time.Second)
+	expect(t, s.ReadHeaderTimeout, 2*time.Second)
+	expect(t, s.WriteTimeout, 3*time.Second)
+	expect(t, s.IdleTimeout, 4*time.Second)
+	expect(t, s.MaxHeaderBytes, 1<<10)
+	expect(t, s.ErrorLog, errorLog)
+	expect(t, s.ShutdownTimeout, 5*time.Second)
+}
+
+func TestServerTLS(t *testing.T) {
+	certFile, keyFile := writeSelfSignedCert(t)
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n := New().WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte("secure"))
+	})
+	s := NewServer(n).Configure(TLS(certFile, keyFile))
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}}
+	res, err := client.Get("https://" + l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+
+	expect(t, res.StatusCode, http.StatusOK)
+	expect(t, string(b), "secure")
+
+	s.Shutdown(context.Background())
+	expect(t, <-done, nil)
+}
+
+func TestRunWithBadTLSFiles(t *testing.T) {
+	err := RunWith(New(), Addr("127.0.0.1:0"), TLS("missing-cert.pem", "missing-key.pem"))
+	if err == nil {
+		t.Error("Expected an error for missing certificate files")
+	}
+}
+
+func TestServerBadTLSFilesSkipsOnStart(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := false
+	s := NewServer(New()).Configure(TLS("missing-cert.pem", "missing-key.pem"))
+	s.OnStart(func() { started = true })
+
+	if err := s.Serve(l); err == nil {
+		t.Error("Expected an error for missing certificate files")
+	}
+	expect(t, started, false)
+}
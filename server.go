@@ -0,0 +1,199 @@
+package nim
+
+import (
+	"context"
+	"crypto/tls"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/nimgo/nim/nimble"
+)
+
+const (
+	// defaultShutdownTimeout is how long in-flight requests are given to drain.
+	defaultShutdownTimeout = 10 * time.Second
+)
+
+// Server runs a nimble stack as an HTTP server and shuts it down gracefully
+// when the process receives SIGINT or SIGTERM.
+type Server struct {
+	*http.Server
+	// ShutdownTimeout is the time allowed for in-flight requests to complete.
+	ShutdownTimeout time.Duration
+
+	logger     *log.Logger
+	certFile   string
+	keyFile    string
+	signals    []os.Signal
+	onStart    []func()
+	onShutdown []func()
+
+	mu       sync.Mutex
+	stopped  chan struct{}
+	isClosed bool
+}
+
+// NewServer returns a new Server for the nimble stack. The addr string takes
+// the same format as http.ListenAndServe.
+func NewServer(n *nimble.Nimble, addr ...string) *Server {
+	return &Server{
+		Server: &http.Server{
+			Addr:    detectAddress(addr...),
+			Handler: n,
+		},
+		ShutdownTimeout: defaultShutdownTimeout,
+		logger:          log.New(os.Stdout, "[n.] ", 0),
+		signals:         []os.Signal{os.Interrupt, syscall.SIGTERM},
+	}
+}
+
+// Configure applies the options to the server.
+func (s *Server) Configure(opts ...Option) *Server {
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// OnStart registers a function that is called once the server is listening.
+func (s *Server) OnStart(fn func()) {
+	s.onStart = append(s.onStart, fn)
+}
+
+// OnShutdown registers a function that is called after the server has stopped.
+// On a shutdown signal or a call to Shutdown, it is called once in-flight requests
+// have drained.
+func (s *Server) OnShutdown(fn func()) {
+	s.onShutdown = append(s.onShutdown, fn)
+}
+
+// ListenAndServe listens on the server address and serves requests until
+// the server is shut down.
+func (s *Server) ListenAndServe() error {
+	l, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// Serve accepts connections on the listener until a shutdown signal is received
+// or Shutdown is called. It returns nil after a graceful shutdown, once in-flight
+// requests have completed and the OnShutdown functions have run.
+func (s *Server) Serve(l net.Listener) error {
+	tlsEnabled := s.certFile != "" || s.keyFile != ""
+	if tlsEnabled {
+		// load the key pair up front, so a bad one fails before the server is announced
+		if err := s.loadKeyPair(); err != nil {
+			l.Close()
+			return err
+		}
+	}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, s.signals...)
+	defer signal.Stop(sig)
+
+	errc := make(chan error, 1)
+	go func() {
+		if tlsEnabled {
+			errc <- s.Server.ServeTLS(l, "", "")
+		} else {
+			errc <- s.Server.Serve(l)
+		}
+	}()
+
+	s.logger.Printf("Server is listening on %s", l.Addr())
+	for _, fn := range s.onStart {
+		fn()
+	}
+
+	var err error
+	select {
+	case err = <-errc:
+		if err != http.ErrServerClosed {
+			return err
+		}
+		// Serve returns as soon as Shutdown is called, before requests have drained
+		<-s.done()
+		err = nil
+	case <-sig:
+		s.logger.Printf("Server is shutting down")
+		err = s.drain()
+		<-errc
+	}
+
+	for _, fn := range s.onShutdown {
+		fn()
+	}
+	return err
+}
+
+// loadKeyPair loads the certificate and key files into the TLS configuration,
+// replacing its certificates as http.Server.ServeTLS does.
+func (s *Server) loadKeyPair() error {
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return err
+	}
+	config := &tls.Config{}
+	if s.TLSConfig != nil {
+		config = s.TLSConfig.Clone()
+	}
+	config.Certificates = []tls.Certificate{cert}
+	s.TLSConfig = config
+	return nil
+}
+
+// drain stops accepting connections and waits up to ShutdownTimeout
+// for in-flight requests to complete.
+func (s *Server) drain() error {
+	ctx := context.Background()
+	if s.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.ShutdownTimeout)
+		defer cancel()
+	}
+	return s.Shutdown(ctx)
+}
+
+// Shutdown gracefully shuts down the server like http.Server.Shutdown. A running
+// Serve returns once it has completed.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.Server.Shutdown(ctx)
+	s.stop()
+	return err
+}
+
+// Close immediately closes the server like http.Server.Close.
+func (s *Server) Close() error {
+	err := s.Server.Close()
+	s.stop()
+	return err
+}
+
+// done returns a channel that is closed once the server has been shut down or closed.
+func (s *Server) done() chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped == nil {
+		s.stopped = make(chan struct{})
+	}
+	return s.stopped
+}
+
+func (s *Server) stop() {
+	done := s.done()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isClosed {
+		s.isClosed = true
+		close(done)
+	}
+}
@@ -0,0 +1,118 @@
+package nim
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestServerGracefulShutdownOnSignal(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	n := New().WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte("drained"))
+	})
+
+	s := NewServer(n)
+	events := ""
+	s.OnStart(func() { events += "start;" })
+	s.OnShutdown(func() { events += "shutdown;" })
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+
+	body := make(chan string, 1)
+	go func() {
+		res, err := http.Get("http://" + l.Addr().String())
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		body <- string(b)
+	}()
+
+	<-started
+	p, _ := os.FindProcess(os.Getpid())
+	p.Signal(os.Interrupt)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Expected a graceful shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+
+	expect(t, <-body, "drained")
+	expect(t, events, "start;shutdown;")
+}
+
+func TestServerShutdown(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewServer(New())
+	shutdown := false
+	s.OnShutdown(func() { shutdown = true })
+	s.OnStart(func() { go s.Shutdown(context.Background()) })
+
+	expect(t, s.Serve(l), nil)
+	expect(t, shutdown, true)
+}
+
+func TestServerShutdownDrainsBeforeHooks(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	started := make(chan struct{})
+	var finished atomic.Bool
+	n := New().WithFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		finished.Store(true)
+	})
+
+	s := NewServer(n)
+	drained := false
+	s.OnShutdown(func() { drained = finished.Load() })
+
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(l) }()
+	go http.Get("http://" + l.Addr().String())
+
+	<-started
+	go s.Shutdown(context.Background())
+
+	select {
+	case err := <-done:
+		expect(t, err, nil)
+	case <-time.After(5 * time.Second):
+		t.Fatal("Server did not shut down")
+	}
+	expect(t, drained, true)
+}
+
+func TestServerListenError(t *testing.T) {
+	s := NewServer(New(), "not-an-address")
+	if s.ListenAndServe() == nil {
+		t.Error("Expected an error for an invalid address")
+	}
+}